@@ -0,0 +1,54 @@
+package txnode
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+)
+
+// AdvisoryLock takes the Postgres transaction-level advisory lock key with
+// pg_advisory_xact_lock, in the node's transaction, beginning it on db if
+// needed, and waits until it is granted. The lock is released when the
+// transaction commits or rolls back, so it guards the whole chain. Use
+// AdvisoryKey to derive a key from a string.
+//
+// Advisory locks need a transaction, so AdvisoryLock fails with ErrNotStarted
+// if txn is nil. Other dialects fail with ErrUnsupported.
+func (txn *TxNode) AdvisoryLock(ctx context.Context, db DB, key int64) error {
+	if err := txn.checkAdvisory(); err != nil {
+		return err
+	}
+	_, err := txn.ExecContext(ctx, db, "SELECT pg_advisory_xact_lock($1)", key)
+	return err
+}
+
+// TryAdvisoryLock is like AdvisoryLock but does not wait: it reports whether
+// the lock was granted, using pg_try_advisory_xact_lock.
+func (txn *TxNode) TryAdvisoryLock(ctx context.Context, db DB, key int64) (bool, error) {
+	if err := txn.checkAdvisory(); err != nil {
+		return false, err
+	}
+	var ok bool
+	err := txn.QueryRowContext(ctx, db, "SELECT pg_try_advisory_xact_lock($1)", key).Scan(&ok)
+	return ok, err
+}
+
+// AdvisoryKey returns the advisory lock key of name, the FNV-1a hash of name.
+// Different names may map to the same key, so two unrelated locks can
+// occasionally serialize each other.
+func AdvisoryKey(name string) int64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(name))
+	return int64(h.Sum64())
+}
+
+// checkAdvisory returns an error if the node cannot take advisory locks.
+func (txn *TxNode) checkAdvisory() error {
+	if txn == nil {
+		return fmt.Errorf("txnode: advisory lock: %w", ErrNotStarted)
+	}
+	if txn.cfg.dialect != DialectPostgres {
+		return fmt.Errorf("txnode: advisory lock: %w", ErrUnsupported)
+	}
+	return nil
+}
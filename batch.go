@@ -0,0 +1,157 @@
+package txnode
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// ChunkResult is the outcome of one chunk of ExecBatch.
+type ChunkResult struct {
+	// Start and End delimit the argument sets of the chunk, argSets[Start:End].
+	Start, End int
+	// RowsAffected is the total number of rows affected by the chunk, as far
+	// as the driver reports them.
+	RowsAffected int64
+	// Err is the error that failed the chunk.
+	Err error
+}
+
+// BatchOption configures ExecBatch.
+type BatchOption func(*batchConfig)
+
+type batchConfig struct {
+	savepoints bool
+}
+
+// WithChunkSavepoints runs every chunk of ExecBatch in a savepoint. A failed
+// chunk is rolled back to its savepoint and the batch goes on with the next
+// one, so the transaction stays usable.
+func WithChunkSavepoints() BatchOption {
+	return func(c *batchConfig) {
+		c.savepoints = true
+	}
+}
+
+// ExecBatch prepares query once and executes it with every argument set of
+// argSets, in the node's transaction, beginning it on db if needed. The
+// argument sets are split into chunks of chunkSize, or a single chunk if
+// chunkSize is not positive, and ExecBatch returns the result of every chunk
+// it ran.
+//
+// Without WithChunkSavepoints, the batch stops at the first failed chunk and
+// returns its error. With it, all chunks run and the error joins the errors of
+// the failed chunks. Savepoints need a transaction, so they fail with
+// ErrNotStarted if txn is nil.
+func (txn *TxNode) ExecBatch(
+	ctx context.Context,
+	db DB,
+	query string,
+	argSets [][]any,
+	chunkSize int,
+	opts ...BatchOption,
+) ([]ChunkResult, error) {
+	var cfg batchConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.savepoints && txn == nil {
+		return nil, fmt.Errorf("txnode: exec batch with savepoints: %w", ErrNotStarted)
+	}
+	if len(argSets) == 0 {
+		return nil, nil
+	}
+	if chunkSize <= 0 {
+		chunkSize = len(argSets)
+	}
+
+	stmt, err := txn.PrepareQuery(ctx, db, query)
+	if err != nil {
+		return nil, err
+	}
+	if txn == nil || !txn.cfg.stmtCache {
+		defer stmt.Close()
+	}
+	if txn != nil {
+		query = txn.rebind(query)
+	}
+
+	var (
+		results []ChunkResult
+		errs    []error
+	)
+	for start := 0; start < len(argSets); start += chunkSize {
+		end := min(start+chunkSize, len(argSets))
+		res := ChunkResult{Start: start, End: end}
+		if cfg.savepoints {
+			res.RowsAffected, res.Err = txn.execChunkSavepoint(ctx, stmt, query, argSets[start:end])
+		} else {
+			res.RowsAffected, res.Err = txn.execChunk(ctx, stmt, query, argSets[start:end])
+		}
+		results = append(results, res)
+
+		if res.Err != nil {
+			err := fmt.Errorf("txnode: exec batch chunk %d-%d: %w", start, end, res.Err)
+			if !cfg.savepoints {
+				return results, err
+			}
+			errs = append(errs, err)
+		}
+	}
+	return results, errors.Join(errs...)
+}
+
+// execChunkSavepoint runs execChunk in a savepoint, rolling back to it if the
+// chunk fails.
+func (txn *TxNode) execChunkSavepoint(
+	ctx context.Context,
+	stmt *sql.Stmt,
+	query string,
+	argSets [][]any,
+) (int64, error) {
+	name := txn.nextSavepoint()
+	if err := txn.Savepoint(ctx, name); err != nil {
+		return 0, err
+	}
+
+	rows, err := txn.execChunk(ctx, stmt, query, argSets)
+	if err != nil {
+		if rbErr := txn.RollbackToSavepoint(ctx, name); rbErr != nil {
+			return rows, errors.Join(err, rbErr)
+		}
+		return rows, err
+	}
+	return rows, txn.ReleaseSavepoint(ctx, name)
+}
+
+// execChunk executes stmt, prepared from query, with every argument set of
+// argSets and returns the number of affected rows.
+func (txn *TxNode) execChunk(ctx context.Context, stmt *sql.Stmt, query string, argSets [][]any) (int64, error) {
+	var total int64
+	for _, args := range argSets {
+		var rows int64
+		exec := func(ctx context.Context) (int64, error) {
+			res, err := stmt.ExecContext(ctx, args...)
+			if err != nil {
+				return -1, err
+			}
+			rows = rowsAffected(res)
+			return rows, nil
+		}
+
+		var err error
+		if txn == nil {
+			_, err = exec(ctx)
+		} else {
+			err = txn.runStmt(ctx, stmtExec, query, args, exec)
+		}
+		if err != nil {
+			return total, err
+		}
+		if rows > 0 {
+			total += rows
+		}
+	}
+	return total, nil
+}
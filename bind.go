@@ -0,0 +1,111 @@
+package txnode
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Bindvar is a style of bind placeholders.
+type Bindvar int
+
+const (
+	// BindQuestion writes placeholders as ?, as MySQL and SQLite do.
+	BindQuestion Bindvar = iota + 1
+	// BindDollar writes placeholders as $1, $2, ..., as Postgres does.
+	BindDollar
+	// BindColon writes placeholders as :1, :2, ..., as Oracle does.
+	BindColon
+	// BindAt writes placeholders as @p1, @p2, ..., as SQL Server does.
+	BindAt
+)
+
+// placeholder returns the placeholder for the n-th argument, counting from 1.
+func (b Bindvar) placeholder(n int) string {
+	switch b {
+	case BindDollar:
+		return "$" + strconv.Itoa(n)
+	case BindColon:
+		return ":" + strconv.Itoa(n)
+	case BindAt:
+		return "@p" + strconv.Itoa(n)
+	default:
+		return "?"
+	}
+}
+
+// numbered reports whether the placeholders of style b are numbered, so that
+// one argument can be referred to several times.
+func (b Bindvar) numbered() bool {
+	return b == BindDollar || b == BindColon || b == BindAt
+}
+
+// bindvar returns the placeholder style of dialect d.
+func (d Dialect) bindvar() Bindvar {
+	switch d {
+	case DialectPostgres:
+		return BindDollar
+	case DialectSQLServer:
+		return BindAt
+	default:
+		return BindQuestion
+	}
+}
+
+// Rebind rewrites the ? placeholders of query into placeholders of style b,
+// numbering them in order. Question marks inside string literals, quoted
+// identifiers and comments are not placeholders. Note that Postgres operators
+// containing ?, such as the jsonb ?| operator, are rewritten as well.
+func Rebind(b Bindvar, query string) string {
+	if b == BindQuestion || !strings.Contains(query, "?") {
+		return query
+	}
+
+	var sb strings.Builder
+	sb.Grow(len(query) + 8)
+	n := 0
+	for i := 0; i < len(query); {
+		if end := skipLiteral(quoting{}, query, i); end > i {
+			sb.WriteString(query[i:end])
+			i = end
+			continue
+		}
+		if query[i] == '?' {
+			n++
+			sb.WriteString(b.placeholder(n))
+		} else {
+			sb.WriteByte(query[i])
+		}
+		i++
+	}
+	return sb.String()
+}
+
+// WithRebind makes the node rewrite the ? placeholders of the statements it
+// runs into placeholders of style b, see Rebind, so that the same query text
+// runs against databases with different placeholder styles. It also selects
+// the placeholders written by NamedExec, NamedQuery and In.
+func WithRebind(b Bindvar) Option {
+	return func(c *config) {
+		c.rebind = b
+	}
+}
+
+// bindvar returns the placeholder style of the statements run through the
+// node: the one set with WithRebind, or else the one of its dialect.
+func (txn *TxNode) bindvar() Bindvar {
+	if txn == nil {
+		return DialectPostgres.bindvar()
+	}
+	if txn.cfg.rebind != 0 {
+		return txn.cfg.rebind
+	}
+	return txn.cfg.dialect.bindvar()
+}
+
+// rebind rewrites query for the placeholder style set with WithRebind.
+func (txn *TxNode) rebind(query string) string {
+	if txn.cfg.rebind == 0 {
+		return query
+	}
+	return Rebind(txn.cfg.rebind, query)
+}
@@ -0,0 +1,34 @@
+package txnode_test
+
+import (
+	"testing"
+
+	"github.com/MartellOnell/txnode"
+)
+
+func TestRebind(t *testing.T) {
+	tests := []struct {
+		name  string
+		b     txnode.Bindvar
+		query string
+		want  string
+	}{
+		{"question", txnode.BindQuestion, "SELECT * FROM t WHERE a = ? AND b = ?", "SELECT * FROM t WHERE a = ? AND b = ?"},
+		{"dollar", txnode.BindDollar, "SELECT * FROM t WHERE a = ? AND b = ?", "SELECT * FROM t WHERE a = $1 AND b = $2"},
+		{"colon", txnode.BindColon, "UPDATE t SET a = ? WHERE id = ?", "UPDATE t SET a = :1 WHERE id = :2"},
+		{"at", txnode.BindAt, "DELETE FROM t WHERE id = ?", "DELETE FROM t WHERE id = @p1"},
+		{"no placeholders", txnode.BindDollar, "SELECT 1", "SELECT 1"},
+		{"string literal", txnode.BindDollar, "SELECT '?' FROM t WHERE a = ?", "SELECT '?' FROM t WHERE a = $1"},
+		{"quoted identifier", txnode.BindDollar, `SELECT "a?" FROM t WHERE a = ?`, `SELECT "a?" FROM t WHERE a = $1`},
+		{"line comment", txnode.BindDollar, "SELECT a -- why?\nFROM t WHERE a = ?", "SELECT a -- why?\nFROM t WHERE a = $1"},
+		{"block comment", txnode.BindAt, "SELECT /* ? */ a FROM t WHERE a = ?", "SELECT /* ? */ a FROM t WHERE a = @p1"},
+		{"dollar quoted", txnode.BindDollar, "SELECT $$?$$, ?", "SELECT $$?$$, $1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := txnode.Rebind(tt.b, tt.query); got != tt.want {
+				t.Errorf("Rebind(%q) = %q, want %q", tt.query, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package txnode
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned when beginning a transaction while the circuit
+// breaker set with WithCircuitBreaker is open.
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// WithCircuitBreaker guards the database with a circuit breaker: after
+// failures consecutive failures to begin or commit a transaction, beginning
+// new transactions fails at once with ErrCircuitOpen for coolDown, sparing
+// the database during an incident and giving callers an immediate error.
+// After the cool-down new transactions are let through again; the next
+// failure reopens the circuit and the next success closes it. Failures caused
+// by canceled contexts are not counted.
+//
+// The breaker is shared by all nodes created with the same Option value, so
+// create the option once, for example for a Manager, rather than per node.
+func WithCircuitBreaker(failures int, coolDown time.Duration) Option {
+	b := &circuitBreaker{threshold: max(failures, 1), coolDown: coolDown}
+	return func(c *config) {
+		c.breaker = b
+	}
+}
+
+type circuitBreaker struct {
+	threshold int
+	coolDown  time.Duration
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+}
+
+// allow returns ErrCircuitOpen if the circuit is open. A nil breaker is
+// always closed.
+func (b *circuitBreaker) allow() error {
+	if b == nil {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures >= b.threshold && time.Now().Before(b.openUntil) {
+		return ErrCircuitOpen
+	}
+	return nil
+}
+
+// record counts the outcome err of beginning or committing a transaction.
+func (b *circuitBreaker) record(err error, c Classifier) {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch {
+	case err == nil:
+		b.failures = 0
+	case c.Classify(err) == CategoryCanceled:
+	default:
+		b.failures++
+		if b.failures >= b.threshold {
+			b.openUntil = time.Now().Add(b.coolDown)
+		}
+	}
+}
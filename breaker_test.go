@@ -0,0 +1,78 @@
+package txnode_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+
+	"github.com/MartellOnell/txnode"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	down := errors.New("database down")
+	mock.ExpectBegin().WillReturnError(down)
+	mock.ExpectBegin().WillReturnError(down)
+	mock.ExpectBegin()
+	mock.ExpectCommit()
+
+	const coolDown = 50 * time.Millisecond
+	breaker := txnode.WithCircuitBreaker(2, coolDown)
+	ctx := context.Background()
+
+	for i := range 2 {
+		if err := txnode.New(breaker).Begin(ctx, db); !errors.Is(err, down) {
+			t.Fatalf("Begin() %d = %v, want %v", i, err, down)
+		}
+	}
+	if err := txnode.New(breaker).Begin(ctx, db); !errors.Is(err, txnode.ErrCircuitOpen) {
+		t.Fatalf("Begin() with the circuit open = %v, want ErrCircuitOpen", err)
+	}
+
+	time.Sleep(coolDown)
+	txn := txnode.New(breaker)
+	txn.SetEnd()
+	if err := txn.Begin(ctx, db); err != nil {
+		t.Fatalf("Begin() after the cool-down = %v", err)
+	}
+	if err := txn.CommitIfNeeded(); err != nil {
+		t.Fatal(err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCircuitBreakerIgnoresCanceled(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	mock.ExpectBegin().WillReturnError(context.Canceled)
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+
+	breaker := txnode.WithCircuitBreaker(1, time.Hour)
+	ctx := context.Background()
+	if err := txnode.New(breaker).Begin(ctx, db); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Begin() = %v, want context.Canceled", err)
+	}
+	txn := txnode.New(breaker)
+	if err := txn.Begin(ctx, db); err != nil {
+		t.Fatalf("Begin() after a canceled begin = %v", err)
+	}
+	if err := txn.RollbackTransaction(); err != nil {
+		t.Fatal(err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,89 @@
+package txnode
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// BulkInsert inserts rows into the columns of table with multi-row INSERT
+// statements run in the node's transaction, beginning it on db if needed. The
+// rows are split into as few statements as the dialect's limit on bind
+// parameters allows, for example 65535 for Postgres. Every row must have one
+// value per column. BulkInsert returns the number of inserted rows as far as
+// the driver reports them.
+//
+// If txn is nil the statements run on db directly, so a failure can leave the
+// rows of the earlier statements inserted.
+func (txn *TxNode) BulkInsert(
+	ctx context.Context,
+	db DB,
+	table string,
+	columns []string,
+	rows [][]any,
+) (int64, error) {
+	if !isQualifiedIdentifier(table) {
+		return 0, fmt.Errorf("txnode: invalid table %q", table)
+	}
+	if len(columns) == 0 {
+		return 0, fmt.Errorf("txnode: bulk insert into %s: no columns", table)
+	}
+	for _, col := range columns {
+		if !isIdentifier(col) {
+			return 0, fmt.Errorf("txnode: invalid column %q", col)
+		}
+	}
+	for i, row := range rows {
+		if len(row) != len(columns) {
+			return 0, fmt.Errorf("txnode: bulk insert into %s: row %d has %d values for %d columns",
+				table, i, len(row), len(columns))
+		}
+	}
+
+	d := txn.dialect()
+	chunk := d.maxParams() / len(columns)
+	if limit := d.maxInsertRows(); limit > 0 && chunk > limit {
+		chunk = limit
+	}
+	if chunk == 0 {
+		return 0, fmt.Errorf("txnode: bulk insert into %s: %d columns exceed the parameter limit", table, len(columns))
+	}
+
+	var total int64
+	for len(rows) > 0 {
+		n := min(chunk, len(rows))
+		query, args := bulkInsertSQL(txn.bindvar(), table, columns, rows[:n])
+		res, err := txn.ExecContext(ctx, db, query, args...)
+		if err != nil {
+			return total, fmt.Errorf("txnode: bulk insert into %s: %w", table, err)
+		}
+		if affected := rowsAffected(res); affected > 0 {
+			total += affected
+		}
+		rows = rows[n:]
+	}
+	return total, nil
+}
+
+// bulkInsertSQL returns the statement inserting rows, with placeholders of
+// style bv, and its arguments.
+func bulkInsertSQL(bv Bindvar, table string, columns []string, rows [][]any) (string, []any) {
+	args := make([]any, 0, len(rows)*len(columns))
+	var b strings.Builder
+	b.WriteString("INSERT INTO " + table + " (" + strings.Join(columns, ", ") + ") VALUES ")
+	for i, row := range rows {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteByte('(')
+		for j, v := range row {
+			if j > 0 {
+				b.WriteString(", ")
+			}
+			args = append(args, v)
+			b.WriteString(bv.placeholder(len(args)))
+		}
+		b.WriteByte(')')
+	}
+	return b.String(), args
+}
@@ -0,0 +1,120 @@
+package txnode_test
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+
+	"github.com/MartellOnell/txnode"
+)
+
+func TestBulkInsert(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO users (id, name) VALUES ($1, $2), ($3, $4)").
+		WithArgs(1, "a", 2, "b").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	txn := txnode.New()
+	txn.SetEnd()
+	n, err := txn.BulkInsert(context.Background(), db, "users", []string{"id", "name"}, [][]any{{1, "a"}, {2, "b"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("BulkInsert() = %d, want 2", n)
+	}
+	if err := txn.CommitIfNeeded(); err != nil {
+		t.Fatal(err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBulkInsertChunks(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	// SQL Server allows at most 1000 rows in one INSERT ... VALUES.
+	rows := make([][]any, 1500)
+	for i := range rows {
+		rows[i] = []any{i}
+	}
+	mock.ExpectBegin()
+	mock.ExpectExec(bulkValues(1000)).WillReturnResult(sqlmock.NewResult(0, 1000))
+	mock.ExpectExec(bulkValues(500)).WillReturnResult(sqlmock.NewResult(0, 500))
+	mock.ExpectCommit()
+
+	txn := txnode.New(txnode.WithDialect(txnode.DialectSQLServer))
+	txn.SetEnd()
+	n, err := txn.BulkInsert(context.Background(), db, "dbo.t", []string{"v"}, rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1500 {
+		t.Errorf("BulkInsert() = %d, want 1500", n)
+	}
+	if err := txn.CommitIfNeeded(); err != nil {
+		t.Fatal(err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// bulkValues returns the SQL Server statement inserting n single-column rows
+// into dbo.t.
+func bulkValues(n int) string {
+	var b strings.Builder
+	b.WriteString("INSERT INTO dbo.t (v) VALUES ")
+	for i := range n {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString("(@p" + strconv.Itoa(i+1) + ")")
+	}
+	return b.String()
+}
+
+func TestBulkInsertInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		table   string
+		columns []string
+		rows    [][]any
+	}{
+		{"table", "users; DROP TABLE users", []string{"id"}, [][]any{{1}}},
+		{"column", "users", []string{"id)"}, [][]any{{1}}},
+		{"no columns", "users", nil, [][]any{{}}},
+		{"short row", "users", []string{"id", "name"}, [][]any{{1, "a"}, {2}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+
+			txn := txnode.New()
+			if _, err := txn.BulkInsert(context.Background(), db, tt.table, tt.columns, tt.rows); err == nil {
+				t.Error("BulkInsert() succeeded, want an error")
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
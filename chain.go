@@ -0,0 +1,113 @@
+package txnode
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidChain is wrapped by the errors returned for a Chain whose
+// operations do not end in exactly one terminal operation.
+var ErrInvalidChain = errors.New("invalid chain")
+
+// Chain runs named operations in one transaction. Operations are added with
+// Then, and the last one with End, which marks the operation that ends the
+// chain: the transaction is committed once it succeeds. A chain whose end
+// operation is missing, repeated or not last is rejected before anything runs,
+// with an error naming the offending operations.
+//
+//	err := txnode.NewChain().
+//		Then("reserve stock", reserve).
+//		End("create order", create).
+//		Run(ctx, db)
+type Chain struct {
+	opts []Option
+	ops  []chainOp
+}
+
+type chainOp struct {
+	name string
+	end  bool
+	fn   func(ctx context.Context, txn *TxNode) error
+}
+
+// NewChain returns an empty chain whose transaction is configured with opts.
+func NewChain(opts ...Option) *Chain {
+	return &Chain{opts: opts}
+}
+
+// Then appends an operation that does not end the chain.
+func (c *Chain) Then(name string, fn func(ctx context.Context, txn *TxNode) error) *Chain {
+	c.ops = append(c.ops, chainOp{name: name, fn: fn})
+	return c
+}
+
+// End appends the operation that ends the chain.
+func (c *Chain) End(name string, fn func(ctx context.Context, txn *TxNode) error) *Chain {
+	c.ops = append(c.ops, chainOp{name: name, end: true, fn: fn})
+	return c
+}
+
+// Validate reports whether the chain has exactly one end operation and
+// whether it is the last one.
+func (c *Chain) Validate() error {
+	var ends []string
+	for _, op := range c.ops {
+		if op.end {
+			ends = append(ends, op.name)
+		}
+	}
+
+	switch {
+	case len(c.ops) == 0:
+		return fmt.Errorf("txnode: %w: no operations", ErrInvalidChain)
+	case len(ends) == 0:
+		return fmt.Errorf("txnode: %w: no end operation after %q",
+			ErrInvalidChain, c.ops[len(c.ops)-1].name)
+	case len(ends) > 1:
+		return fmt.Errorf("txnode: %w: %d end operations: %s",
+			ErrInvalidChain, len(ends), quoteNames(ends))
+	case !c.ops[len(c.ops)-1].end:
+		return fmt.Errorf("txnode: %w: end operation %q is followed by %q",
+			ErrInvalidChain, ends[0], c.ops[len(c.ops)-1].name)
+	}
+	return nil
+}
+
+// Run validates the chain and runs its operations in order in one transaction
+// begun on db, as Run does for a single function. The transaction is rolled
+// back on the first failing operation, and the error is prefixed with the
+// operation's name. Operations must not end the transaction themselves; an
+// operation that does fails the chain.
+func (c *Chain) Run(ctx context.Context, db DB) error {
+	if err := c.Validate(); err != nil {
+		return err
+	}
+
+	return Run(ctx, db, func(ctx context.Context, txn *TxNode) error {
+		for _, op := range c.ops {
+			err := txn.profile(ctx, op.name, func(ctx context.Context) error {
+				return op.fn(ctx, txn)
+			})
+			if err != nil {
+				return fmt.Errorf("%s: %w", op.name, err)
+			}
+			if txn.IsDone() {
+				return fmt.Errorf("txnode: %w: operation %q ended the transaction",
+					ErrInvalidChain, op.name)
+			}
+			txn.UnsetEnd()
+		}
+		return nil
+	}, c.opts...)
+}
+
+// quoteNames formats names as a comma-separated list of quoted strings.
+func quoteNames(names []string) string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = fmt.Sprintf("%q", name)
+	}
+	return strings.Join(quoted, ", ")
+}
@@ -0,0 +1,327 @@
+package txnode
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"strings"
+)
+
+// Category is the kind of failure a database error represents.
+type Category int
+
+const (
+	// CategoryUnknown is an error no classifier recognized.
+	CategoryUnknown Category = iota
+	// CategorySerialization is a serialization failure under SERIALIZABLE or
+	// REPEATABLE READ isolation.
+	CategorySerialization
+	// CategoryDeadlock is a deadlock detected by the server.
+	CategoryDeadlock
+	// CategoryLockTimeout is a failure to acquire a lock in time.
+	CategoryLockTimeout
+	// CategoryBusy is SQLite lock contention (SQLITE_BUSY, SQLITE_LOCKED).
+	CategoryBusy
+	// CategoryConnection is a broken or unusable connection.
+	CategoryConnection
+	// CategoryConstraint is an integrity constraint violation.
+	CategoryConstraint
+	// CategoryTimeout is an exceeded deadline or statement timeout.
+	CategoryTimeout
+	// CategoryCanceled is a canceled context or query.
+	CategoryCanceled
+)
+
+// String returns the name of the category.
+func (c Category) String() string {
+	switch c {
+	case CategorySerialization:
+		return "serialization"
+	case CategoryDeadlock:
+		return "deadlock"
+	case CategoryLockTimeout:
+		return "lock_timeout"
+	case CategoryBusy:
+		return "busy"
+	case CategoryConnection:
+		return "connection"
+	case CategoryConstraint:
+		return "constraint"
+	case CategoryTimeout:
+		return "timeout"
+	case CategoryCanceled:
+		return "canceled"
+	default:
+		return "unknown"
+	}
+}
+
+// Retryable reports whether running the whole transaction again may succeed
+// after an error of this category.
+func (c Category) Retryable() bool {
+	switch c {
+	case CategorySerialization, CategoryDeadlock, CategoryLockTimeout, CategoryBusy:
+		return true
+	default:
+		return false
+	}
+}
+
+// Classifier sorts driver errors into categories and decides which of them
+// are worth retrying. It is used by all retry machinery of the package.
+type Classifier interface {
+	Classify(err error) Category
+	IsRetryable(err error) bool
+}
+
+// WithClassifier sets the classifier used to decide which errors are retried.
+// The default is DefaultClassifier.
+func WithClassifier(c Classifier) Option {
+	return func(cfg *config) {
+		cfg.classifier = c
+	}
+}
+
+// DefaultClassifier returns a classifier that recognizes the errors of pgx,
+// lib/pq, go-sql-driver/mysql, mattn/go-sqlite3 and modernc.org/sqlite, as well
+// as context and database/sql errors.
+func DefaultClassifier() Classifier {
+	return ChainClassifier(PostgresClassifier{}, MySQLClassifier{}, SQLiteClassifier{}, contextClassifier{})
+}
+
+// Classify returns the category of err according to DefaultClassifier.
+func Classify(err error) Category {
+	return DefaultClassifier().Classify(err)
+}
+
+// ChainClassifier returns a classifier that asks each of cs in turn and uses
+// the first category other than CategoryUnknown.
+func ChainClassifier(cs ...Classifier) Classifier {
+	return chainClassifier(cs)
+}
+
+type chainClassifier []Classifier
+
+func (cs chainClassifier) Classify(err error) Category {
+	for _, c := range cs {
+		if cat := c.Classify(err); cat != CategoryUnknown {
+			return cat
+		}
+	}
+	return CategoryUnknown
+}
+
+func (cs chainClassifier) IsRetryable(err error) bool {
+	for _, c := range cs {
+		if c.Classify(err) != CategoryUnknown {
+			return c.IsRetryable(err)
+		}
+	}
+	return false
+}
+
+// PostgresClassifier classifies Postgres errors by their SQLSTATE code, as
+// reported by pgx and lib/pq.
+type PostgresClassifier struct{}
+
+// Classify implements Classifier.
+func (PostgresClassifier) Classify(err error) Category {
+	code := sqlState(err)
+	switch {
+	case code == "":
+		return CategoryUnknown
+	case code == "40001":
+		return CategorySerialization
+	case code == "40P01":
+		return CategoryDeadlock
+	case code == "55P03":
+		return CategoryLockTimeout
+	case code == "57014":
+		return CategoryTimeout
+	case strings.HasPrefix(code, "08"):
+		return CategoryConnection
+	case strings.HasPrefix(code, "23"):
+		return CategoryConstraint
+	default:
+		return CategoryUnknown
+	}
+}
+
+// IsRetryable implements Classifier.
+func (c PostgresClassifier) IsRetryable(err error) bool {
+	return c.Classify(err).Retryable()
+}
+
+// MySQL server error numbers.
+const (
+	mysqlErrLockWaitTimeout = 1205
+	mysqlErrDeadlock        = 1213
+	mysqlErrDupEntry        = 1062
+	mysqlErrRowIsReferenced = 1451
+	mysqlErrNoReferencedRow = 1452
+	mysqlErrQueryTimeout    = 3024
+	mysqlErrLockNoWait      = 3572
+	// Client errors for a connection the server closed or dropped.
+	mysqlErrServerGone = 2006
+	mysqlErrServerLost = 2013
+)
+
+// MySQLClassifier classifies go-sql-driver/mysql errors by server error number.
+type MySQLClassifier struct{}
+
+// Classify implements Classifier.
+func (MySQLClassifier) Classify(err error) Category {
+	n, ok := mysqlErrorNumber(err)
+	if !ok {
+		return CategoryUnknown
+	}
+
+	switch n {
+	case mysqlErrDeadlock:
+		return CategoryDeadlock
+	case mysqlErrLockWaitTimeout, mysqlErrLockNoWait:
+		return CategoryLockTimeout
+	case mysqlErrQueryTimeout:
+		return CategoryTimeout
+	case mysqlErrDupEntry, mysqlErrRowIsReferenced, mysqlErrNoReferencedRow:
+		return CategoryConstraint
+	case mysqlErrServerGone, mysqlErrServerLost:
+		return CategoryConnection
+	default:
+		return CategoryUnknown
+	}
+}
+
+// IsRetryable implements Classifier.
+func (c MySQLClassifier) IsRetryable(err error) bool {
+	return c.Classify(err).Retryable()
+}
+
+// SQLiteClassifier classifies mattn/go-sqlite3 and modernc.org/sqlite errors.
+type SQLiteClassifier struct{}
+
+// Classify implements Classifier.
+func (SQLiteClassifier) Classify(err error) Category {
+	if IsBusy(err) {
+		return CategoryBusy
+	}
+
+	var cat Category
+	walkErrors(err, func(e error) bool {
+		code, ok := sqliteErrorCode(e)
+		if ok && code&0xff == sqliteConstraint {
+			cat = CategoryConstraint
+			return true
+		}
+		return false
+	})
+	return cat
+}
+
+// IsRetryable implements Classifier.
+func (c SQLiteClassifier) IsRetryable(err error) bool {
+	return c.Classify(err).Retryable()
+}
+
+// contextClassifier classifies context and database/sql errors.
+type contextClassifier struct{}
+
+func (contextClassifier) Classify(err error) Category {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return CategoryTimeout
+	case errors.Is(err, context.Canceled):
+		return CategoryCanceled
+	case errors.Is(err, driver.ErrBadConn):
+		return CategoryConnection
+	default:
+		return CategoryUnknown
+	}
+}
+
+func (c contextClassifier) IsRetryable(err error) bool {
+	return c.Classify(err).Retryable()
+}
+
+// IsSerializationFailure reports whether err is a Postgres serialization
+// failure (SQLSTATE 40001). The transaction that hit it has been aborted and
+// can only succeed when run again from the start.
+func IsSerializationFailure(err error) bool {
+	return PostgresClassifier{}.Classify(err) == CategorySerialization
+}
+
+// IsDeadlock reports whether err is a deadlock detected by Postgres
+// (SQLSTATE 40P01) or MySQL (error 1213). The server has rolled back the
+// transaction, which should be run again.
+func IsDeadlock(err error) bool {
+	return PostgresClassifier{}.Classify(err) == CategoryDeadlock ||
+		MySQLClassifier{}.Classify(err) == CategoryDeadlock
+}
+
+// IsLockWaitTimeout reports whether err is a MySQL lock wait timeout (error 1205).
+func IsLockWaitTimeout(err error) bool {
+	n, ok := mysqlErrorNumber(err)
+	return ok && n == mysqlErrLockWaitTimeout
+}
+
+// sqlState returns the SQLSTATE code carried by err, or an empty string.
+// It understands the errors of pgx and lib/pq without importing either driver.
+func sqlState(err error) string {
+	var e interface{ SQLState() string }
+	if errors.As(err, &e) {
+		return e.SQLState()
+	}
+	return ""
+}
+
+// mysqlErrorNumber returns the server error number carried by err.
+// It recognizes go-sql-driver/mysql's MySQLError by its Number field, so the
+// driver does not have to be imported.
+func mysqlErrorNumber(err error) (uint16, bool) {
+	var (
+		number uint16
+		found  bool
+	)
+	walkErrors(err, func(e error) bool {
+		v := reflect.ValueOf(e)
+		if v.Kind() == reflect.Pointer {
+			v = v.Elem()
+		}
+		if v.Kind() != reflect.Struct || v.Type().Name() != "MySQLError" {
+			return false
+		}
+
+		f := v.FieldByName("Number")
+		if !f.IsValid() || f.Kind() != reflect.Uint16 {
+			return false
+		}
+
+		number, found = uint16(f.Uint()), true
+		return true
+	})
+	return number, found
+}
+
+// walkErrors calls fn for err and every error it wraps, depth first, until fn
+// returns true.
+func walkErrors(err error, fn func(error) bool) bool {
+	if err == nil {
+		return false
+	}
+	if fn(err) {
+		return true
+	}
+
+	switch e := err.(type) {
+	case interface{ Unwrap() error }:
+		return walkErrors(e.Unwrap(), fn)
+	case interface{ Unwrap() []error }:
+		for _, err := range e.Unwrap() {
+			if walkErrors(err, fn) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,78 @@
+package txnode_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/MartellOnell/txnode"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		want      txnode.Category
+		retryable bool
+	}{
+		{"nil", nil, txnode.CategoryUnknown, false},
+		{"plain", errors.New("boom"), txnode.CategoryUnknown, false},
+		{"postgres serialization", pgError("40001"), txnode.CategorySerialization, true},
+		{"postgres deadlock", pgError("40P01"), txnode.CategoryDeadlock, true},
+		{"postgres lock timeout", pgError("55P03"), txnode.CategoryLockTimeout, true},
+		{"postgres statement timeout", pgError("57014"), txnode.CategoryTimeout, false},
+		{"postgres connection", pgError("08006"), txnode.CategoryConnection, false},
+		{"postgres unique violation", pgError("23505"), txnode.CategoryConstraint, false},
+		{"postgres wrapped", fmt.Errorf("insert: %w", pgError("40001")), txnode.CategorySerialization, true},
+		{"mysql deadlock", &MySQLError{Number: 1213}, txnode.CategoryDeadlock, true},
+		{"mysql lock wait timeout", &MySQLError{Number: 1205}, txnode.CategoryLockTimeout, true},
+		{"mysql nowait", &MySQLError{Number: 3572}, txnode.CategoryLockTimeout, true},
+		{"mysql query timeout", &MySQLError{Number: 3024}, txnode.CategoryTimeout, false},
+		{"mysql duplicate entry", &MySQLError{Number: 1062}, txnode.CategoryConstraint, false},
+		{"mysql server gone", &MySQLError{Number: 2006}, txnode.CategoryConnection, false},
+		{"sqlite busy", errors.New("database is locked"), txnode.CategoryBusy, true},
+		{"deadline", context.DeadlineExceeded, txnode.CategoryTimeout, false},
+		{"canceled", fmt.Errorf("query: %w", context.Canceled), txnode.CategoryCanceled, false},
+		{"bad connection", driver.ErrBadConn, txnode.CategoryConnection, false},
+		{"joined", errors.Join(errors.New("rollback failed"), pgError("40P01")), txnode.CategoryDeadlock, true},
+	}
+	c := txnode.DefaultClassifier()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := c.Classify(tt.err); got != tt.want {
+				t.Errorf("Classify(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+			if got := c.IsRetryable(tt.err); got != tt.retryable {
+				t.Errorf("IsRetryable(%v) = %v, want %v", tt.err, got, tt.retryable)
+			}
+		})
+	}
+}
+
+func TestChainClassifierFirstMatchWins(t *testing.T) {
+	err := fmt.Errorf("begin: %w", pgError("40001"))
+	c := txnode.ChainClassifier(txnode.MySQLClassifier{}, txnode.PostgresClassifier{})
+	if got := c.Classify(err); got != txnode.CategorySerialization {
+		t.Errorf("Classify() = %v, want %v", got, txnode.CategorySerialization)
+	}
+	if got := txnode.ChainClassifier(txnode.MySQLClassifier{}).Classify(err); got != txnode.CategoryUnknown {
+		t.Errorf("MySQL only Classify() = %v, want %v", got, txnode.CategoryUnknown)
+	}
+}
+
+func TestErrorPredicates(t *testing.T) {
+	if !txnode.IsSerializationFailure(pgError("40001")) {
+		t.Error("IsSerializationFailure(40001) = false")
+	}
+	if !txnode.IsDeadlock(pgError("40P01")) || !txnode.IsDeadlock(&MySQLError{Number: 1213}) {
+		t.Error("IsDeadlock = false for a Postgres or MySQL deadlock")
+	}
+	if !txnode.IsLockWaitTimeout(&MySQLError{Number: 1205}) || txnode.IsLockWaitTimeout(pgError("55P03")) {
+		t.Error("IsLockWaitTimeout does not match MySQL error 1205 only")
+	}
+	if !txnode.IsBusy(errors.New("database table is locked")) || txnode.IsBusy(pgError("40001")) {
+		t.Error("IsBusy does not match SQLite lock errors only")
+	}
+}
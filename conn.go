@@ -0,0 +1,15 @@
+package txnode
+
+import "database/sql"
+
+// NewOnConn creates a node that begins its transaction on conn instead of on a
+// *sql.DB, so session-level state set on the connection applies to the chain.
+// The db arguments of the node's methods are ignored. The node closes conn,
+// returning it to the pool, once the transaction is committed or rolled back,
+// or when beginning it fails or the node is closed before its first statement.
+func NewOnConn(conn *sql.Conn, opts ...Option) *TxNode {
+	txn := New(opts...)
+	txn.conn = conn
+	txn.onConn = true
+	return txn
+}
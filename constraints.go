@@ -0,0 +1,55 @@
+package txnode
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// SetConstraintsDeferred defers the checks of the named deferrable
+// constraints to the commit of the node's transaction, beginning it on db if
+// needed, so that rows with circular foreign keys can be inserted in any
+// order. It issues SET CONSTRAINTS ... DEFERRED and is supported for Postgres
+// only; other dialects fail with ErrUnsupported.
+func (txn *TxNode) SetConstraintsDeferred(ctx context.Context, db DB, names ...string) error {
+	if len(names) == 0 {
+		return fmt.Errorf("txnode: set constraints deferred: no constraints")
+	}
+	for _, name := range names {
+		if !isQualifiedIdentifier(name) {
+			return fmt.Errorf("txnode: invalid constraint %q", name)
+		}
+	}
+	if d := txn.dialect(); d != DialectPostgres {
+		return fmt.Errorf("txnode: set constraints deferred: %w", ErrUnsupported)
+	}
+	return txn.deferConstraints(ctx, db, "SET CONSTRAINTS "+strings.Join(names, ", ")+" DEFERRED")
+}
+
+// SetAllConstraintsDeferred defers the checks of all deferrable constraints
+// to the commit of the node's transaction, beginning it on db if needed. It
+// issues SET CONSTRAINTS ALL DEFERRED for Postgres and, for SQLite, enables
+// PRAGMA defer_foreign_keys, which SQLite resets when the transaction ends.
+// Other dialects fail with ErrUnsupported.
+func (txn *TxNode) SetAllConstraintsDeferred(ctx context.Context, db DB) error {
+	switch txn.dialect() {
+	case DialectPostgres:
+		return txn.deferConstraints(ctx, db, "SET CONSTRAINTS ALL DEFERRED")
+	case DialectSQLite:
+		return txn.deferConstraints(ctx, db, "PRAGMA defer_foreign_keys = ON")
+	default:
+		return fmt.Errorf("txnode: set constraints deferred: %w", ErrUnsupported)
+	}
+}
+
+// deferConstraints runs query, which defers constraint checks, in the node's
+// transaction.
+func (txn *TxNode) deferConstraints(ctx context.Context, db DB, query string) error {
+	if txn == nil {
+		return fmt.Errorf("txnode: set constraints deferred: %w", ErrNotStarted)
+	}
+	if _, err := txn.ExecContext(ctx, db, query); err != nil {
+		return fmt.Errorf("txnode: set constraints deferred: %w", err)
+	}
+	return nil
+}
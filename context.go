@@ -0,0 +1,17 @@
+package txnode
+
+import "context"
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx that carries txn.
+func NewContext(ctx context.Context, txn *TxNode) context.Context {
+	return context.WithValue(ctx, contextKey{}, txn)
+}
+
+// FromContext returns the node stored in ctx by NewContext. It returns nil when
+// ctx carries no node, which the node's methods treat as non-transactional mode.
+func FromContext(ctx context.Context) *TxNode {
+	txn, _ := ctx.Value(contextKey{}).(*TxNode)
+	return txn
+}
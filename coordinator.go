@@ -0,0 +1,286 @@
+package txnode
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrInDoubt is wrapped by the error Coordinator.Commit returns when the
+// commit decision has been made but could not be applied to every
+// participant. The remaining prepared transactions are resolved by Recover.
+var ErrInDoubt = errors.New("transaction in doubt")
+
+// Branch is the part of a distributed transaction prepared on one participant.
+type Branch struct {
+	// Participant is the name the participant was registered with.
+	Participant string
+	// GID is the global transaction ID the branch was prepared under.
+	GID string
+}
+
+// Decision is the commit decision of a distributed transaction, as written to
+// a RecoveryLog between the prepare and the commit phase.
+type Decision struct {
+	ID       string
+	Branches []Branch
+}
+
+// RecoveryLog durably records the commit decisions of a Coordinator, so that
+// transactions left in doubt by a crash between the two phases can be
+// committed by Recover.
+type RecoveryLog interface {
+	// Commit records d. The Coordinator commits the branches only after
+	// Commit has returned without error.
+	Commit(ctx context.Context, d Decision) error
+	// Forget removes the decision with the given ID once all of its branches
+	// have been committed.
+	Forget(ctx context.Context, id string) error
+	// Pending returns the decisions that have not been forgotten.
+	Pending(ctx context.Context) ([]Decision, error)
+}
+
+// Coordinator commits one logical operation across several Postgres
+// databases with a two-phase commit. Each database gets its own node, see
+// Node. Commit prepares the transactions of all of them and commits them only
+// once all are prepared and the decision has been recorded in the recovery
+// log; any failure before that point rolls all of them back.
+type Coordinator struct {
+	id    string
+	log   RecoveryLog
+	opts  []Option
+	mu    sync.Mutex
+	parts []*participant
+}
+
+type participant struct {
+	name string
+	db   DB
+	txn  *TxNode
+}
+
+// NewCoordinator returns a coordinator that records its decisions in log and
+// configures the nodes it creates with opts. A nil log disables recovery:
+// transactions left in doubt must then be resolved by hand.
+func NewCoordinator(log RecoveryLog, opts ...Option) *Coordinator {
+	return &Coordinator{
+		id:   newID(),
+		log:  log,
+		opts: opts,
+	}
+}
+
+// ID returns the identifier of the distributed transaction.
+func (c *Coordinator) ID() string {
+	return c.id
+}
+
+// Node returns the node of the participant name, registering it with db on
+// first use. The node is the end of its own chain but is committed by Commit,
+// not by CommitIfNeeded. opts are applied after the coordinator's options.
+// name becomes part of the global transaction ID of the participant's branch
+// and may only contain letters, digits and the characters _ - . and :.
+func (c *Coordinator) Node(name string, db DB, opts ...Option) *TxNode {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, p := range c.parts {
+		if p.name == name {
+			return p.txn
+		}
+	}
+
+	txn := New(append(c.opts[:len(c.opts):len(c.opts)], opts...)...)
+	c.parts = append(c.parts, &participant{name: name, db: db, txn: txn})
+	return txn
+}
+
+// Commit runs the two phases of the commit. Participants whose transaction
+// has not been begun are skipped. If the decision could not be applied to all
+// participants, the returned error wraps ErrInDoubt.
+func (c *Coordinator) Commit(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	d := Decision{ID: c.id}
+	var prepared []*participant
+	for _, p := range c.parts {
+		if _, ok := p.txn.Tx(); !ok {
+			continue
+		}
+		gid := branchGID(c.id, p.name)
+		if err := p.txn.PrepareTransaction(ctx, gid); err != nil {
+			err = fmt.Errorf("txnode: prepare %s: %w", p.name, err)
+			return errors.Join(err, c.abort(ctx, prepared))
+		}
+		prepared = append(prepared, p)
+		d.Branches = append(d.Branches, Branch{Participant: p.name, GID: gid})
+	}
+
+	if c.log != nil {
+		if err := c.log.Commit(ctx, d); err != nil {
+			err = fmt.Errorf("txnode: record commit decision: %w", err)
+			return errors.Join(err, c.abort(ctx, prepared))
+		}
+	}
+
+	var errs []error
+	for i, p := range prepared {
+		if err := CommitPrepared(ctx, p.db, d.Branches[i].GID); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("txnode: %w: %s: %w", ErrInDoubt, c.id, errors.Join(errs...))
+	}
+
+	if c.log != nil {
+		if err := c.log.Forget(ctx, c.id); err != nil {
+			return fmt.Errorf("txnode: forget commit decision: %w", err)
+		}
+	}
+	return nil
+}
+
+// Rollback rolls back the transactions of all participants.
+func (c *Coordinator) Rollback(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.abort(ctx, nil)
+}
+
+// abort rolls back the participants whose transaction is still open and the
+// prepared transactions of prepared.
+func (c *Coordinator) abort(ctx context.Context, prepared []*participant) error {
+	var errs []error
+	for _, p := range c.parts {
+		if p.txn.IsActive() {
+			if err := p.txn.RollbackTransaction(); err != nil {
+				errs = append(errs, fmt.Errorf("txnode: rollback %s: %w", p.name, err))
+			}
+		}
+	}
+	for _, p := range prepared {
+		if err := RollbackPrepared(ctx, p.db, branchGID(c.id, p.name)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Recover commits the branches of the decisions pending in log, using dbs to
+// look up the database of each participant by name, and forgets the decisions
+// whose branches have all been committed. Branches that no longer exist, for
+// example because they were committed before a crash, are skipped.
+func Recover(ctx context.Context, log RecoveryLog, dbs map[string]DB) error {
+	pending, err := log.Pending(ctx)
+	if err != nil {
+		return fmt.Errorf("txnode: read recovery log: %w", err)
+	}
+
+	var errs []error
+	for _, d := range pending {
+		ok := true
+		for _, b := range d.Branches {
+			db, found := dbs[b.Participant]
+			if !found {
+				errs = append(errs, fmt.Errorf("txnode: recover %s: unknown participant %q", d.ID, b.Participant))
+				ok = false
+				continue
+			}
+			if err := CommitPrepared(ctx, db, b.GID); err != nil && !isUndefinedObject(err) {
+				errs = append(errs, err)
+				ok = false
+			}
+		}
+		if ok {
+			if err := log.Forget(ctx, d.ID); err != nil {
+				errs = append(errs, fmt.Errorf("txnode: forget commit decision: %w", err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// GIDPrefix starts the global transaction IDs of the branches prepared by a
+// Coordinator. Branches with this prefix that are listed by ListPrepared but
+// have no decision in the recovery log were left behind by a coordinator that
+// failed before recording its decision, and can be rolled back once they are
+// older than any running distributed transaction.
+const GIDPrefix = "txnode."
+
+// branchGID returns the global transaction ID of a participant's branch.
+func branchGID(id, participant string) string {
+	return GIDPrefix + id + "." + participant
+}
+
+// isUndefinedObject reports whether err is the Postgres error raised for a
+// prepared transaction that does not exist.
+func isUndefinedObject(err error) bool {
+	return sqlState(err) == "42704"
+}
+
+// NewSQLRecoveryLog returns a Postgres RecoveryLog stored in table on db. db may
+// be the database of a participant: the log is written in transactions of its
+// own. The table must exist with the following layout:
+//
+//	CREATE TABLE txnode_decisions (
+//		id          text NOT NULL,
+//		participant text NOT NULL,
+//		gid         text NOT NULL,
+//		PRIMARY KEY (id, participant)
+//	)
+func NewSQLRecoveryLog(db DB, table string) (RecoveryLog, error) {
+	if !isIdentifier(table) {
+		return nil, fmt.Errorf("txnode: invalid recovery log table %q", table)
+	}
+	return &sqlRecoveryLog{db: db, table: table}, nil
+}
+
+type sqlRecoveryLog struct {
+	db    DB
+	table string
+}
+
+func (l *sqlRecoveryLog) Commit(ctx context.Context, d Decision) error {
+	return Run(ctx, l.db, func(ctx context.Context, txn *TxNode) error {
+		for _, b := range d.Branches {
+			_, err := txn.ExecContext(ctx, l.db,
+				"INSERT INTO "+l.table+" (id, participant, gid) VALUES ($1, $2, $3)",
+				d.ID, b.Participant, b.GID)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (l *sqlRecoveryLog) Forget(ctx context.Context, id string) error {
+	_, err := l.db.ExecContext(ctx, "DELETE FROM "+l.table+" WHERE id = $1", id)
+	return err
+}
+
+func (l *sqlRecoveryLog) Pending(ctx context.Context) ([]Decision, error) {
+	rows, err := l.db.QueryContext(ctx, "SELECT id, participant, gid FROM "+l.table+" ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Decision
+	for rows.Next() {
+		var id string
+		var b Branch
+		if err := rows.Scan(&id, &b.Participant, &b.GID); err != nil {
+			return nil, err
+		}
+		if len(out) == 0 || out[len(out)-1].ID != id {
+			out = append(out, Decision{ID: id})
+		}
+		last := &out[len(out)-1]
+		last.Branches = append(last.Branches, b)
+	}
+	return out, rows.Err()
+}
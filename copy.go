@@ -0,0 +1,111 @@
+package txnode
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// CopySource supplies the rows of CopyFrom. Its methods mirror those of
+// pgx.CopyFromSource.
+type CopySource interface {
+	// Next advances to the next row and reports whether there is one.
+	Next() bool
+	// Values returns the values of the current row.
+	Values() ([]any, error)
+	// Err returns the error, if any, that ended the rows.
+	Err() error
+}
+
+// CopyFromRows returns a CopySource over rows.
+func CopyFromRows(rows [][]any) CopySource {
+	return &copyRows{rows: rows, i: -1}
+}
+
+type copyRows struct {
+	rows [][]any
+	i    int
+}
+
+func (r *copyRows) Next() bool {
+	r.i++
+	return r.i < len(r.rows)
+}
+
+func (r *copyRows) Values() ([]any, error) { return r.rows[r.i], nil }
+func (r *copyRows) Err() error             { return nil }
+
+// CopyFrom bulk-loads the rows of src into the columns of table with the
+// Postgres COPY protocol, in the node's transaction, beginning it on db if
+// needed, so that the load commits or rolls back with the statements around
+// it. It returns the number of rows copied.
+//
+// CopyFrom issues COPY ... FROM STDIN as a prepared statement executed once
+// per row and once more to flush, which is how lib/pq exposes the protocol.
+// With pgx use pgxnode.TxNode.CopyFrom instead. Other dialects fail with
+// ErrUnsupported. COPY needs a single connection, so CopyFrom fails with
+// ErrNotStarted if txn is nil.
+func (txn *TxNode) CopyFrom(
+	ctx context.Context,
+	db DB,
+	table string,
+	columns []string,
+	src CopySource,
+) (int64, error) {
+	if txn == nil {
+		return 0, fmt.Errorf("txnode: copy into %s: %w", table, ErrNotStarted)
+	}
+	if txn.cfg.dialect != DialectPostgres {
+		return 0, fmt.Errorf("txnode: copy into %s: %w", table, ErrUnsupported)
+	}
+	if !isQualifiedIdentifier(table) {
+		return 0, fmt.Errorf("txnode: invalid table %q", table)
+	}
+	for _, col := range columns {
+		if !isIdentifier(col) {
+			return 0, fmt.Errorf("txnode: invalid column %q", col)
+		}
+	}
+
+	tx, err := txn.activeTx(ctx, db)
+	if err != nil {
+		return 0, err
+	}
+
+	query := "COPY " + table + " (" + strings.Join(columns, ", ") + ") FROM STDIN"
+	var copied int64
+	err = txn.runStmt(ctx, stmtExec, query, nil, func(ctx context.Context) (int64, error) {
+		stmt, err := tx.PrepareContext(ctx, query)
+		if err != nil {
+			return -1, err
+		}
+		defer stmt.Close()
+
+		for src.Next() {
+			values, err := src.Values()
+			if err != nil {
+				return -1, err
+			}
+			if len(values) != len(columns) {
+				return -1, fmt.Errorf("row has %d values for %d columns", len(values), len(columns))
+			}
+			if _, err := stmt.ExecContext(ctx, values...); err != nil {
+				return -1, err
+			}
+		}
+		if err := src.Err(); err != nil {
+			return -1, err
+		}
+
+		res, err := stmt.ExecContext(ctx)
+		if err != nil {
+			return -1, err
+		}
+		copied = rowsAffected(res)
+		return copied, nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("txnode: copy into %s: %w", table, err)
+	}
+	return copied, nil
+}
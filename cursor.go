@@ -0,0 +1,89 @@
+package txnode
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// ErrCursorClosed is returned by Fetch on a closed cursor.
+var ErrCursorClosed = errors.New("cursor closed")
+
+// Cursor streams the rows of a query in batches, see DeclareCursor.
+type Cursor struct {
+	txn    *TxNode
+	db     DB
+	name   string
+	query  string
+	args   []any
+	offset int
+	closed bool
+}
+
+// DeclareCursor opens a cursor over the rows of query in the node's
+// transaction, beginning it on db if needed, so that a chain can process a
+// large result in batches without holding it in memory. On Postgres it is a
+// server-side cursor declared with DECLARE; it lives as long as the
+// transaction and is closed when it ends. Other dialects emulate the cursor
+// by appending LIMIT and OFFSET clauses to query for every batch, OFFSET and
+// FETCH NEXT on SQL Server, so query needs an ORDER BY clause for the batches
+// to be stable. A nil txn fails with ErrNotStarted.
+func (txn *TxNode) DeclareCursor(ctx context.Context, db DB, query string, args ...any) (*Cursor, error) {
+	if txn == nil {
+		return nil, fmt.Errorf("txnode: declare cursor: %w", ErrNotStarted)
+	}
+
+	c := &Cursor{txn: txn, db: db, query: query, args: args}
+	if txn.cfg.dialect != DialectPostgres {
+		return c, nil
+	}
+
+	root := txn.root()
+	root.cursors++
+	c.name = "txnode_cur_" + strconv.Itoa(root.cursors)
+	if _, err := txn.ExecContext(ctx, db, "DECLARE "+c.name+" NO SCROLL CURSOR FOR "+query, args...); err != nil {
+		return nil, fmt.Errorf("txnode: declare cursor: %w", err)
+	}
+	return c, nil
+}
+
+// Fetch returns the next batch of at most n rows of the cursor. A batch
+// without rows means that the cursor is exhausted. The rows must be closed
+// before the next call to Fetch.
+func (c *Cursor) Fetch(ctx context.Context, n int) (*sql.Rows, error) {
+	if c.closed {
+		return nil, ErrCursorClosed
+	}
+	if n <= 0 {
+		return nil, fmt.Errorf("txnode: fetch %d rows", n)
+	}
+
+	if c.name != "" {
+		return c.txn.QueryContext(ctx, c.db, "FETCH FORWARD "+strconv.Itoa(n)+" FROM "+c.name)
+	}
+
+	query := c.query
+	if c.txn.cfg.dialect == DialectSQLServer {
+		query += " OFFSET " + strconv.Itoa(c.offset) + " ROWS FETCH NEXT " + strconv.Itoa(n) + " ROWS ONLY"
+	} else {
+		query += " LIMIT " + strconv.Itoa(n) + " OFFSET " + strconv.Itoa(c.offset)
+	}
+	c.offset += n
+	return c.txn.QueryContext(ctx, c.db, query, c.args...)
+}
+
+// Close closes the cursor. Closing it is optional, as the cursor ends with
+// the transaction, but frees its server resources earlier.
+func (c *Cursor) Close(ctx context.Context) error {
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	if c.name == "" || c.txn.State() != StateActive {
+		return nil
+	}
+	_, err := c.txn.ExecContext(ctx, c.db, "CLOSE "+c.name)
+	return err
+}
@@ -0,0 +1,61 @@
+package txnode
+
+import (
+	"context"
+	"database/sql"
+)
+
+// Beginner begins transactions.
+type Beginner interface {
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+}
+
+// Preparer prepares statements.
+type Preparer interface {
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+}
+
+// Execer executes statements that return no rows.
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// Querier executes statements that return rows.
+type Querier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// DB is the database handle accepted by the node's methods. The node begins its
+// transaction through BeginTx and uses the other methods in non-transactional
+// mode. *sql.DB and *sql.Conn implement it, as do wrappers around them.
+type DB interface {
+	Beginner
+	Preparer
+	Execer
+	Querier
+}
+
+var (
+	_ DB = (*sql.DB)(nil)
+	_ DB = (*sql.Conn)(nil)
+)
+
+// Node is the part of TxNode that services run their statements through and
+// end the chain with. Accepting a Node rather than a *TxNode lets tests pass
+// a fake, such as txtest.Mock.
+type Node interface {
+	PrepareQuery(ctx context.Context, db DB, query string) (*sql.Stmt, error)
+	ExecContext(ctx context.Context, db DB, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, db DB, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, db DB, query string, args ...any) *Row
+	SetEnd()
+	UnsetEnd()
+	CommitIfNeeded() error
+	RollbackTransaction() error
+}
+
+var (
+	_ Node = (*TxNode)(nil)
+	_ Node = (*SyncTxNode)(nil)
+)
@@ -0,0 +1,81 @@
+package txnode
+
+import (
+	"log/slog"
+	"runtime"
+	"strconv"
+	"sync"
+)
+
+// maxDebugFrames bounds the call stack recorded by WithDebug.
+const maxDebugFrames = 64
+
+// WithDebug records the call stack that began the transaction and the
+// callsite of every PrepareQuery call. They are included in leak warnings,
+// in the records logged when the transaction is aborted or exceeds its
+// maximum duration, and in the debug record logged after a rollback, to help
+// find the code path that forgot to end a chain. Recording the stacks has a
+// cost, so WithDebug is meant for development and troubleshooting.
+func WithDebug() Option {
+	return func(c *config) {
+		c.debug = true
+	}
+}
+
+// debugInfo holds what WithDebug records about a transaction. It is shared
+// with the leak check and must not refer to the node.
+type debugInfo struct {
+	beginStack string
+
+	mu       sync.Mutex
+	prepares []string
+}
+
+// debugBegin records the stack that began the transaction.
+func (txn *TxNode) debugBegin() {
+	if !txn.cfg.debug {
+		return
+	}
+
+	pcs := make([]uintptr, maxDebugFrames)
+	pcs = pcs[:runtime.Callers(3, pcs)]
+	txn.debug = &debugInfo{beginStack: formatStack(pcs)}
+}
+
+// debugPrepare records the callsite of a PrepareQuery call.
+func (txn *TxNode) debugPrepare(query string) {
+	dbg := txn.root().debug
+	if dbg == nil {
+		return
+	}
+
+	site := "unknown"
+	if pc, file, line, ok := runtime.Caller(2); ok {
+		site = runtime.FuncForPC(pc).Name() + " " + file + ":" + strconv.Itoa(line)
+	}
+
+	dbg.mu.Lock()
+	defer dbg.mu.Unlock()
+	dbg.prepares = append(dbg.prepares, site+": "+query)
+}
+
+// attrs returns the recorded stacks as log attributes. It is safe to call on
+// a nil debugInfo.
+func (d *debugInfo) attrs() []slog.Attr {
+	if d == nil {
+		return nil
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return []slog.Attr{
+		slog.String("begin_stack", d.beginStack),
+		slog.Any("prepare_sites", append([]string(nil), d.prepares...)),
+	}
+}
+
+// debugAttrs returns attrs followed by what WithDebug recorded for the node's
+// transaction.
+func (txn *TxNode) debugAttrs(attrs ...slog.Attr) []slog.Attr {
+	return append(attrs, txn.root().debug.attrs()...)
+}
@@ -0,0 +1,84 @@
+package txnode
+
+import (
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DebugHandler returns an HTTP handler listing the transactions registered
+// with WithRegistry, oldest first, with their age, label, state, statement
+// count, last statement and the caller that began them. It renders an HTML
+// table, or JSON if the request has format=json in its query or accepts
+// application/json. Statements are shown sanitized, see SanitizeQuery. Mount
+// it next to the pprof handlers:
+//
+//	mux.Handle("/debug/txnode", txnode.DebugHandler())
+func DebugHandler() http.Handler {
+	return http.HandlerFunc(serveDebug)
+}
+
+type debugTx struct {
+	ID          string    `json:"id"`
+	Label       string    `json:"label,omitempty"`
+	State       string    `json:"state"`
+	Began       time.Time `json:"began"`
+	Age         string    `json:"age"`
+	AgeSeconds  float64   `json:"age_seconds"`
+	Statements  int       `json:"statements"`
+	LastQuery   string    `json:"last_query,omitempty"`
+	Fingerprint string    `json:"fingerprint,omitempty"`
+	Caller      string    `json:"caller"`
+}
+
+func serveDebug(w http.ResponseWriter, r *http.Request) {
+	active := ActiveTransactions()
+	txs := make([]debugTx, len(active))
+	for i, info := range active {
+		txs[i] = debugTx{
+			ID:         info.ID,
+			Label:      info.Label,
+			State:      info.State.String(),
+			Began:      info.Began,
+			Age:        info.Age.Round(time.Millisecond).String(),
+			AgeSeconds: info.Age.Seconds(),
+			Statements: info.Statements,
+			Caller:     info.Caller,
+		}
+		if info.LastQuery != "" {
+			txs[i].LastQuery = SanitizeQuery(info.LastQuery)
+			txs[i].Fingerprint = Fingerprint(info.LastQuery)
+		}
+	}
+
+	if r.URL.Query().Get("format") == "json" || strings.Contains(r.Header.Get("Accept"), "application/json") {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(txs)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_ = debugPage.Execute(w, txs)
+}
+
+var debugPage = template.Must(template.New("txnode").Parse(`<!DOCTYPE html>
+<html>
+<head><title>txnode: active transactions</title>
+<style>
+body { font-family: sans-serif; font-size: 14px; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; vertical-align: top; }
+td.query { font-family: monospace; }
+</style>
+</head>
+<body>
+<h1>{{len .}} active transactions</h1>
+<table>
+<tr><th>ID</th><th>Label</th><th>State</th><th>Age</th><th>Statements</th><th>Last statement</th><th>Fingerprint</th><th>Caller</th></tr>
+{{range .}}<tr><td>{{.ID}}</td><td>{{.Label}}</td><td>{{.State}}</td><td>{{.Age}}</td><td>{{.Statements}}</td><td class="query">{{.LastQuery}}</td><td>{{.Fingerprint}}</td><td>{{.Caller}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
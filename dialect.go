@@ -0,0 +1,88 @@
+package txnode
+
+import "strings"
+
+// Dialect identifies the SQL flavor of the database a node talks to. It selects
+// the SQL the node generates itself, for example for savepoints.
+type Dialect int
+
+const (
+	// DialectPostgres targets PostgreSQL. It is the default dialect.
+	DialectPostgres Dialect = iota
+	// DialectMySQL targets MySQL and MariaDB.
+	DialectMySQL
+	// DialectSQLite targets SQLite.
+	DialectSQLite
+	// DialectSQLServer targets Microsoft SQL Server.
+	DialectSQLServer
+)
+
+// String returns the name of the dialect.
+func (d Dialect) String() string {
+	switch d {
+	case DialectPostgres:
+		return "postgres"
+	case DialectMySQL:
+		return "mysql"
+	case DialectSQLite:
+		return "sqlite"
+	case DialectSQLServer:
+		return "sqlserver"
+	default:
+		return "unknown"
+	}
+}
+
+// WithDialect sets the SQL dialect of the database the node talks to.
+func WithDialect(d Dialect) Option {
+	return func(c *config) {
+		c.dialect = d
+	}
+}
+
+// placeholder returns the bind placeholder for the n-th argument, counting
+// from 1, of a statement in dialect d.
+func (d Dialect) placeholder(n int) string {
+	return d.bindvar().placeholder(n)
+}
+
+// maxParams returns the maximum number of bind parameters of a statement in
+// dialect d.
+func (d Dialect) maxParams() int {
+	switch d {
+	case DialectSQLite:
+		return 32766
+	case DialectSQLServer:
+		return 2100
+	default:
+		return 65535
+	}
+}
+
+// maxInsertRows returns the maximum number of rows of an INSERT ... VALUES
+// statement in dialect d, or 0 if there is no limit besides maxParams.
+func (d Dialect) maxInsertRows() int {
+	if d == DialectSQLServer {
+		return 1000
+	}
+	return 0
+}
+
+// dialect returns the dialect of the node, which is the default dialect for a
+// nil node.
+func (txn *TxNode) dialect() Dialect {
+	if txn == nil {
+		return DialectPostgres
+	}
+	return txn.cfg.dialect
+}
+
+// isQualifiedIdentifier reports whether name is an identifier optionally
+// qualified with a schema, such as public.outbox.
+func isQualifiedIdentifier(name string) bool {
+	schema, table, ok := strings.Cut(name, ".")
+	if !ok {
+		return isIdentifier(name)
+	}
+	return isIdentifier(schema) && isIdentifier(table)
+}
@@ -0,0 +1,26 @@
+package txnode
+
+import "log/slog"
+
+// WithDryRun previews what a chain would do: its statements run and are
+// logged as with WithQueryLogging, at info level unless another level is set
+// there, but CommitIfNeeded rolls the transaction back instead of committing
+// it, without running the commit hooks. Statements are logged to the logger
+// set with WithLogger, so a dry run without one runs silently.
+func WithDryRun() Option {
+	return func(c *config) {
+		c.dryRun = true
+	}
+}
+
+// rollbackDryRun ends the transaction of a node created with WithDryRun in
+// place of the commit, returning the error the commit would have failed with
+// if the transaction was aborted.
+func (txn *TxNode) rollbackDryRun() error {
+	err := txn.Err()
+	txn.log(slog.LevelInfo, "dry run: roll back instead of commit")
+	if rbErr := txn.rollback(nil); rbErr != nil {
+		return rbErr
+	}
+	return err
+}
@@ -0,0 +1,66 @@
+package txnode
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// ErrTxTimeout is the reason recorded on a node whose transaction was rolled
+// back for exceeding the duration set with WithMaxDuration.
+var ErrTxTimeout = errors.New("transaction exceeded its maximum duration")
+
+// maxRecentQueries bounds the statement history kept for diagnostics.
+const maxRecentQueries = 20
+
+// WithMaxDuration limits how long the transaction may stay open. When d has
+// elapsed since begin, the node logs a warning with the elapsed time and the
+// most recent statements, rolls the transaction back and is aborted with an
+// error wrapping ErrAborted and ErrTxTimeout, see Err.
+func WithMaxDuration(d time.Duration) Option {
+	return func(c *config) {
+		c.maxDuration = d
+	}
+}
+
+// startTimer arms the maximum duration timer if it is enabled.
+func (txn *TxNode) startTimer() {
+	d := txn.cfg.maxDuration
+	if d <= 0 {
+		return
+	}
+
+	timer := time.AfterFunc(d, func() {
+		elapsed := time.Since(txn.began)
+		txn.log(slog.LevelWarn, "transaction exceeded maximum duration", txn.debugAttrs(
+			slog.Duration("max_duration", d),
+			slog.Any("statements", txn.recentQueries()),
+		)...)
+		txn.abort(fmt.Errorf("%w: %w after %s", ErrAborted, ErrTxTimeout, elapsed))
+	})
+	txn.stopTimer = timer.Stop
+}
+
+// record remembers query as one of the most recent statements of the chain.
+func (txn *TxNode) record(query string) {
+	root := txn.root()
+
+	root.mu.Lock()
+	defer root.mu.Unlock()
+
+	if len(root.recent) == maxRecentQueries {
+		copy(root.recent, root.recent[1:])
+		root.recent = root.recent[:maxRecentQueries-1]
+	}
+	root.recent = append(root.recent, query)
+}
+
+// recentQueries returns a copy of the most recent statements of the chain.
+func (txn *TxNode) recentQueries() []string {
+	root := txn.root()
+
+	root.mu.Lock()
+	defer root.mu.Unlock()
+	return append([]string(nil), root.recent...)
+}
@@ -0,0 +1,112 @@
+package txnode
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// ErrAlreadyCommitted is returned when a node whose transaction has been
+// committed is committed or rolled back again. It wraps sql.ErrTxDone.
+var ErrAlreadyCommitted = fmt.Errorf("transaction already committed: %w", sql.ErrTxDone)
+
+// ErrTxClosed is returned by a statement run through a node whose transaction
+// has already ended, see TxClosedError. It wraps sql.ErrTxDone.
+var ErrTxClosed = fmt.Errorf("transaction closed: %w", sql.ErrTxDone)
+
+// TxClosedError reports a statement run through a node whose transaction has
+// been committed, rolled back or prepared. It wraps ErrTxClosed.
+type TxClosedError struct {
+	ID    string
+	State State
+	// By is the function, file and line outside this package that ended the
+	// transaction, if known.
+	By string
+}
+
+func (e *TxClosedError) Error() string {
+	if e.By == "" {
+		return fmt.Sprintf("txnode: tx %s already %s", e.ID, e.State)
+	}
+	return fmt.Sprintf("txnode: tx %s already %s by %s", e.ID, e.State, e.By)
+}
+
+func (e *TxClosedError) Unwrap() error {
+	return ErrTxClosed
+}
+
+// Phase names the step of committing a transaction that failed, see CommitError.
+type Phase string
+
+const (
+	// PhaseBeforeCommit is the run of the hooks registered with BeforeCommit.
+	PhaseBeforeCommit Phase = "before commit"
+	// PhaseCommit is the commit of the transaction itself.
+	PhaseCommit Phase = "commit"
+)
+
+// BeginError reports a failure to begin a transaction.
+type BeginError struct {
+	// ID is the ID the transaction would have had, see TxNode.ID.
+	ID  string
+	Err error
+}
+
+func (e *BeginError) Error() string {
+	return fmt.Sprintf("txnode: begin tx %s: %v", e.ID, e.Err)
+}
+
+func (e *BeginError) Unwrap() error {
+	return e.Err
+}
+
+// CommitError reports a failure to commit a transaction. The transaction has
+// been rolled back.
+type CommitError struct {
+	ID    string
+	Phase Phase
+	Err   error
+}
+
+func (e *CommitError) Error() string {
+	return fmt.Sprintf("txnode: %s tx %s: %v", e.Phase, e.ID, e.Err)
+}
+
+func (e *CommitError) Unwrap() error {
+	return e.Err
+}
+
+// RollbackError reports a failure to roll back a transaction.
+type RollbackError struct {
+	ID string
+	// Op is the operation that failed and caused the rollback, as passed to
+	// RollbackTransactionAndLog, or "" if it is unknown.
+	Op  string
+	Err error
+}
+
+func (e *RollbackError) Error() string {
+	if e.Op != "" {
+		return fmt.Sprintf("txnode: %s: rollback tx %s: %v", e.Op, e.ID, e.Err)
+	}
+	return fmt.Sprintf("txnode: rollback tx %s: %v", e.ID, e.Err)
+}
+
+func (e *RollbackError) Unwrap() error {
+	return e.Err
+}
+
+// StmtError reports a failure to prepare or run a statement in a transaction.
+type StmtError struct {
+	ID string
+	// Op is "prepare", "exec" or "query".
+	Op  string
+	Err error
+}
+
+func (e *StmtError) Error() string {
+	return fmt.Sprintf("txnode: %s in tx %s: %v", e.Op, e.ID, e.Err)
+}
+
+func (e *StmtError) Unwrap() error {
+	return e.Err
+}
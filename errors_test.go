@@ -0,0 +1,66 @@
+package txnode_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+
+	"github.com/MartellOnell/txnode"
+)
+
+func TestStatementErrorsCarryTransactionID(t *testing.T) {
+	failure := errors.New("relation does not exist")
+	tests := []struct {
+		op  string
+		run func(ctx context.Context, txn *txnode.TxNode, db txnode.DB) error
+	}{
+		{"exec", func(ctx context.Context, txn *txnode.TxNode, db txnode.DB) error {
+			_, err := txn.ExecContext(ctx, db, "UPDATE t SET v = 1")
+			return err
+		}},
+		{"query", func(ctx context.Context, txn *txnode.TxNode, db txnode.DB) error {
+			_, err := txn.QueryContext(ctx, db, "UPDATE t SET v = 1")
+			return err
+		}},
+		{"prepare", func(ctx context.Context, txn *txnode.TxNode, db txnode.DB) error {
+			_, err := txn.PrepareQuery(ctx, db, "UPDATE t SET v = 1")
+			return err
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.op, func(t *testing.T) {
+			db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+			if err != nil {
+				t.Fatal(err)
+			}
+			mock.ExpectBegin()
+			switch tt.op {
+			case "exec":
+				mock.ExpectExec("UPDATE t SET v = 1").WillReturnError(failure)
+			case "query":
+				mock.ExpectQuery("UPDATE t SET v = 1").WillReturnError(failure)
+			case "prepare":
+				mock.ExpectPrepare("UPDATE t SET v = 1").WillReturnError(failure)
+			}
+			mock.ExpectRollback()
+
+			txn := txnode.New()
+			err = tt.run(context.Background(), txn, db)
+			var stmtErr *txnode.StmtError
+			if !errors.As(err, &stmtErr) {
+				t.Fatalf("error %v is not a *StmtError", err)
+			}
+			if stmtErr.ID != txn.ID() || stmtErr.Op != tt.op {
+				t.Errorf("StmtError{ID: %q, Op: %q}, want {ID: %q, Op: %q}", stmtErr.ID, stmtErr.Op, txn.ID(), tt.op)
+			}
+			if !errors.Is(err, failure) {
+				t.Errorf("error %v does not wrap the driver's", err)
+			}
+			if err := txn.RollbackTransaction(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
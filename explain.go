@@ -0,0 +1,174 @@
+package txnode
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// explainSavepoint is the savepoint the EXPLAIN of a slow statement runs
+// behind, so that its failure or the statement's second execution is undone.
+const explainSavepoint = "txnode_explain"
+
+// WithSlowQueryExplain captures the execution plan of every statement exceeding
+// the threshold set with WithSlowQueryThreshold, by running it again under
+// EXPLAIN in the same transaction, and attaches the plan to the slow statement
+// log and to the StmtEvent passed to the observers. EXPLAIN runs behind a
+// savepoint that is rolled back, so that a failure to explain does not fail
+// the transaction. With analyze the statement is run under EXPLAIN ANALYZE,
+// whose effects the savepoint undoes so that they are not applied twice;
+// SQLite has no EXPLAIN ANALYZE and always reports its query plan. SQL Server
+// is not supported.
+//
+// The result of a query run with QueryContext or QueryRowContext still
+// occupies the connection when the query is found to be slow, so its plan is
+// captured once the rows are closed or the row is scanned, before the next
+// statement of the chain or the end of the transaction, and the slow query is
+// logged then. Queries are explained without ANALYZE, which would take as
+// long as the query again, and their StmtEvent carries no plan.
+func WithSlowQueryExplain(analyze bool) Option {
+	return func(c *config) {
+		c.explainSlow = true
+		c.explainAnalyze = analyze
+	}
+}
+
+// explain returns the plan of query for WithSlowQueryExplain, or "" if none
+// could be captured.
+func (txn *TxNode) explain(ctx context.Context, query string, args []any, analyze bool) string {
+	if !txn.cfg.explainSlow {
+		return ""
+	}
+
+	var prefix string
+	switch txn.cfg.dialect {
+	case DialectPostgres, DialectMySQL:
+		prefix = "EXPLAIN "
+		if analyze {
+			prefix = "EXPLAIN ANALYZE "
+		}
+	case DialectSQLite:
+		prefix, analyze = "EXPLAIN QUERY PLAN ", false
+	default:
+		return ""
+	}
+
+	// A failed EXPLAIN aborts a Postgres transaction, so it always runs
+	// behind a savepoint, which also undoes the execution of ANALYZE.
+	root := txn.root()
+	if err := root.Savepoint(ctx, explainSavepoint); err != nil {
+		txn.log(slog.LevelWarn, "explain slow statement", slog.Any("error", err))
+		return ""
+	}
+	plan, err := root.queryPlan(ctx, prefix+query, args)
+	if rbErr := root.RollbackToSavepoint(ctx, explainSavepoint); rbErr != nil {
+		// The statement may have been applied twice, or the transaction
+		// left failed.
+		root.abort(fmt.Errorf("txnode: undo explain: %w", rbErr))
+		return ""
+	}
+	err = errors.Join(err, root.ReleaseSavepoint(ctx, explainSavepoint))
+	if err != nil {
+		txn.log(slog.LevelWarn, "explain slow statement", slog.Any("error", err))
+		return ""
+	}
+	return plan
+}
+
+// slowQuery is a slow query waiting for its result to be read before its plan
+// is captured, see WithSlowQueryExplain.
+type slowQuery struct {
+	query string
+	args  []any
+	d     time.Duration
+	// open reports whether the result still occupies the connection.
+	open func() bool
+}
+
+// deferExplain records a slow query whose plan is captured by explainQueries.
+func (txn *TxNode) deferExplain(query string, args []any, d time.Duration) {
+	root := txn.root()
+	root.slowQueries = append(root.slowQueries, &slowQuery{query: query, args: args, d: d})
+}
+
+// watchSlowQuery sets the probe of the slow query just recorded by the
+// statement that returned its result.
+func (txn *TxNode) watchSlowQuery(open func() bool) {
+	for _, q := range txn.root().slowQueries {
+		if q.open == nil {
+			q.open = open
+		}
+	}
+}
+
+// explainQueries captures the plans of the slow queries whose result has been
+// read and logs them. With end the transaction is about to end, and the
+// queries whose result is still open are logged without a plan.
+func (txn *TxNode) explainQueries(ctx context.Context, end bool) {
+	root := txn.root()
+	if len(root.slowQueries) == 0 {
+		return
+	}
+
+	var pending []*slowQuery
+	for _, q := range root.slowQueries {
+		switch {
+		case q.open == nil || !q.open():
+			plan := root.explain(ctx, q.query, q.args, false)
+			root.logSlowStmt(stmtQuery, q.query, q.d, plan)
+		case end:
+			root.logSlowStmt(stmtQuery, q.query, q.d, "")
+		default:
+			pending = append(pending, q)
+		}
+	}
+	root.slowQueries = pending
+}
+
+// logSlowQueries logs the slow queries left once the transaction has ended,
+// without a plan.
+func (txn *TxNode) logSlowQueries() {
+	for _, q := range txn.slowQueries {
+		txn.logSlowStmt(stmtQuery, q.query, q.d, "")
+	}
+	txn.slowQueries = nil
+}
+
+// queryPlan runs an EXPLAIN statement on the transaction and returns its
+// rows, one per line with the columns separated by tabs.
+func (txn *TxNode) queryPlan(ctx context.Context, query string, args []any) (string, error) {
+	rows, err := txn.tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return "", err
+	}
+	vals := make([]any, len(cols))
+	ptrs := make([]any, len(cols))
+	for i := range vals {
+		ptrs[i] = &vals[i]
+	}
+
+	var lines []string
+	for rows.Next() {
+		if err := rows.Scan(ptrs...); err != nil {
+			return "", err
+		}
+		fields := make([]string, len(vals))
+		for i, v := range vals {
+			if b, ok := v.([]byte); ok {
+				v = string(b)
+			}
+			fields[i] = fmt.Sprint(v)
+		}
+		lines = append(lines, strings.Join(fields, "\t"))
+	}
+	return strings.Join(lines, "\n"), rows.Err()
+}
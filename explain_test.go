@@ -0,0 +1,44 @@
+package txnode_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+
+	"github.com/MartellOnell/txnode"
+)
+
+func TestSlowQueryExplainFailureKeepsTransaction(t *testing.T) {
+	for _, analyze := range []bool{false, true} {
+		db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		if err != nil {
+			t.Fatal(err)
+		}
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE t SET v = $1").WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("SAVEPOINT txnode_explain").WillReturnResult(sqlmock.NewResult(0, 0))
+		explain := "EXPLAIN UPDATE t SET v = $1"
+		if analyze {
+			explain = "EXPLAIN ANALYZE UPDATE t SET v = $1"
+		}
+		mock.ExpectQuery(explain).WithArgs(1).WillReturnError(errors.New("cannot explain"))
+		mock.ExpectExec("ROLLBACK TO SAVEPOINT txnode_explain").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("RELEASE SAVEPOINT txnode_explain").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		txn := txnode.New(txnode.WithSlowQueryThreshold(time.Nanosecond), txnode.WithSlowQueryExplain(analyze))
+		txn.SetEnd()
+		if _, err := txn.ExecContext(context.Background(), db, "UPDATE t SET v = $1", 1); err != nil {
+			t.Fatal(err)
+		}
+		if err := txn.CommitIfNeeded(); err != nil {
+			t.Fatalf("analyze %v: CommitIfNeeded() = %v", analyze, err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Fatalf("analyze %v: %v", analyze, err)
+		}
+	}
+}
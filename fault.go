@@ -0,0 +1,123 @@
+package txnode
+
+import (
+	"errors"
+	"math/rand/v2"
+	"strings"
+	"sync"
+)
+
+// ErrInjected is the error of a Fault without an error of its own.
+var ErrInjected = errors.New("injected fault")
+
+// FaultPoint is where a Fault strikes.
+type FaultPoint int
+
+const (
+	// FaultBegin fails beginning the transaction.
+	FaultBegin FaultPoint = iota
+	// FaultStatement fails preparing or executing a statement.
+	FaultStatement
+	// FaultCommit fails the commit, after rolling the transaction back.
+	FaultCommit
+	// FaultRollback fails the rollback, after rolling the transaction back.
+	FaultRollback
+)
+
+// Fault describes a failure to inject, see NewFaultInjector.
+type Fault struct {
+	Point FaultPoint
+	// Query restricts a FaultStatement fault to the statements containing it.
+	// An empty Query matches every statement.
+	Query string
+	// Err is the error the fault returns. A nil Err stands for ErrInjected.
+	Err error
+	// Probability is the chance, between 0 and 1, that the fault strikes at
+	// a matching occasion. Zero means always.
+	Probability float64
+	// After is the number of matching occasions to let pass before the
+	// fault strikes.
+	After int
+	// Times is the number of times the fault strikes at most. Zero means no
+	// limit.
+	Times int
+}
+
+// FaultInjector fails the operations of the nodes it is attached to with
+// WithFaultInjection, to exercise retry, rollback and compensation paths in
+// tests without patching the driver. It is safe for concurrent use.
+type FaultInjector struct {
+	mu     sync.Mutex
+	rand   *rand.Rand
+	faults []*faultState
+}
+
+type faultState struct {
+	Fault
+	seen     int
+	injected int
+}
+
+// NewFaultInjector returns an injector of faults. Faults are considered in
+// order and the first one that strikes wins. Probabilities are drawn from a
+// random source seeded with seed, so that a run can be reproduced.
+func NewFaultInjector(seed uint64, faults ...Fault) *FaultInjector {
+	fi := &FaultInjector{rand: rand.New(rand.NewPCG(seed, seed))}
+	for _, f := range faults {
+		fi.faults = append(fi.faults, &faultState{Fault: f})
+	}
+	return fi
+}
+
+// Injected returns the number of faults injected so far.
+func (fi *FaultInjector) Injected() int {
+	fi.mu.Lock()
+	defer fi.mu.Unlock()
+
+	n := 0
+	for _, f := range fi.faults {
+		n += f.injected
+	}
+	return n
+}
+
+// WithFaultInjection attaches fi to the node. It is meant for tests only.
+func WithFaultInjection(fi *FaultInjector) Option {
+	return func(c *config) {
+		c.faults = fi
+	}
+}
+
+// inject returns the error of the first fault striking at point for query,
+// or nil. A nil injector never strikes.
+func (fi *FaultInjector) inject(point FaultPoint, query string) error {
+	if fi == nil {
+		return nil
+	}
+
+	fi.mu.Lock()
+	defer fi.mu.Unlock()
+
+	for _, f := range fi.faults {
+		if f.Point != point || point == FaultStatement && !strings.Contains(query, f.Query) {
+			continue
+		}
+		if f.Times > 0 && f.injected >= f.Times {
+			continue
+		}
+		f.seen++
+		if f.seen <= f.After {
+			continue
+		}
+		if f.Probability > 0 && fi.rand.Float64() >= f.Probability {
+			continue
+		}
+
+		f.injected++
+		if f.Err == nil {
+			return ErrInjected
+		}
+		return f.Err
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package txnode
+
+import (
+	"log/slog"
+	"strconv"
+	"time"
+)
+
+// HistoryEntry records one statement of a transaction, see WithHistory.
+type HistoryEntry struct {
+	// Kind is "prepare", "exec" or "query".
+	Kind string
+	// Query is the statement with its literals replaced, see SanitizeQueryDialect.
+	Query       string
+	Fingerprint string
+	Start       time.Time
+	Duration    time.Duration
+	// RowsAffected is the number of rows affected by an executed statement,
+	// or -1 if it is unknown.
+	RowsAffected int64
+	Err          error
+}
+
+// WithHistory records every statement prepared or executed through the node,
+// for an audit of what a business operation ran. The record is returned by
+// History and logged as a single record at info level when the transaction is
+// committed or rolled back, to the logger set with WithLogger.
+func WithHistory() Option {
+	return func(c *config) {
+		c.history = true
+	}
+}
+
+// History returns the statements run in the node's transaction so far, in
+// order, if the node was created with WithHistory. Statements of Nested
+// children are part of the transaction of their parent.
+func (txn *TxNode) History() []HistoryEntry {
+	if txn == nil || !txn.cfg.history {
+		return nil
+	}
+	return txn.statements()
+}
+
+// statements returns a copy of the statements recorded for WithHistory or
+// WithPostMortem.
+func (txn *TxNode) statements() []HistoryEntry {
+	root := txn.root()
+
+	root.mu.Lock()
+	defer root.mu.Unlock()
+	return append([]HistoryEntry(nil), root.history...)
+}
+
+// addHistory records a statement for WithHistory, or only the last ones for
+// WithPostMortem.
+func (txn *TxNode) addHistory(kind stmtKind, query string, start time.Time, d time.Duration, rows int64, err error) {
+	if !txn.cfg.history && txn.cfg.postMortem <= 0 {
+		return
+	}
+	root := txn.root()
+
+	root.mu.Lock()
+	defer root.mu.Unlock()
+	if !txn.cfg.history && len(root.history) == txn.cfg.postMortem {
+		copy(root.history, root.history[1:])
+		root.history = root.history[:len(root.history)-1]
+	}
+	root.history = append(root.history, HistoryEntry{
+		Kind:         kind.String(),
+		Query:        txn.sanitize(query),
+		Fingerprint:  Fingerprint(query),
+		Start:        start,
+		Duration:     d,
+		RowsAffected: rows,
+		Err:          err,
+	})
+}
+
+// logHistory logs the statements of the transaction once it has ended.
+func (txn *TxNode) logHistory(committed bool, reason error) {
+	if !txn.cfg.history || !enabled(txn.logContext(), txn.logger(), slog.LevelInfo) {
+		return
+	}
+
+	history := txn.History()
+	attrs := []slog.Attr{
+		slog.Bool("committed", committed),
+		slog.Int("statement_count", len(history)),
+		historyGroup(history),
+	}
+	if reason != nil {
+		attrs = append(attrs, slog.Any("reason", reason))
+	}
+	txn.log(slog.LevelInfo, "transaction history", attrs...)
+}
+
+// historyGroup returns the statements of history as a group of groups keyed
+// by their position.
+func historyGroup(history []HistoryEntry) slog.Attr {
+	stmts := make([]any, len(history))
+	for i, h := range history {
+		attrs := []any{
+			slog.String("kind", h.Kind),
+			slog.String("query", h.Query),
+			slog.String("fingerprint", h.Fingerprint),
+			slog.Duration("stmt_duration", h.Duration),
+		}
+		if h.RowsAffected >= 0 {
+			attrs = append(attrs, slog.Int64("rows_affected", h.RowsAffected))
+		}
+		if h.Err != nil {
+			attrs = append(attrs, slog.Any("error", h.Err))
+		}
+		stmts[i] = slog.Group(strconv.Itoa(i), attrs...)
+	}
+	return slog.Group("statements", stmts...)
+}
@@ -0,0 +1,123 @@
+package txnode
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+)
+
+// OnCommit registers fn to run after the transaction has been committed
+// successfully. Hooks run in registration order once CommitIfNeeded commits
+// the end node, and are discarded if the transaction is rolled back. Hooks
+// registered on a Nested child are handed to its parent when the child's
+// savepoint is released. They do not run for nodes with external ownership.
+// The context passed to fn carries the values of the context the transaction
+// was begun with, but is never canceled.
+//
+// If txn is nil there is no transaction to wait for and fn runs immediately.
+func (txn *TxNode) OnCommit(fn func(ctx context.Context)) {
+	if txn == nil {
+		fn(context.Background())
+		return
+	}
+	txn.onCommit = append(txn.onCommit, fn)
+}
+
+// BeforeCommit registers fn to run inside CommitIfNeeded right before the
+// transaction is committed, for example to flush buffered writes through tx.
+// Hooks run in registration order; if one returns an error the remaining hooks
+// are skipped, the transaction is rolled back and CommitIfNeeded returns that
+// error. Hooks registered on a Nested child are handed to its parent when the
+// child's savepoint is released. BeforeCommit has no effect if txn is nil.
+func (txn *TxNode) BeforeCommit(fn func(ctx context.Context, tx *sql.Tx) error) {
+	if txn == nil {
+		return
+	}
+	txn.beforeCommit = append(txn.beforeCommit, fn)
+}
+
+// runBeforeCommitHooks runs the hooks registered with BeforeCommit and rolls
+// the transaction back if one of them fails.
+func (txn *TxNode) runBeforeCommitHooks() error {
+	ctx := txn.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	for _, fn := range txn.beforeCommit {
+		if err := fn(ctx, txn.tx); err != nil {
+			err = &CommitError{ID: txn.id, Phase: PhaseBeforeCommit, Err: err}
+			txn.log(slog.LevelError, "before commit hook", slog.Any("error", err))
+			txn.resetSession(txn.tx)
+			rollbackErr := txn.tx.Rollback()
+			txn.notifyEnd(false, err)
+			txn.runRollbackHooks(err)
+			if rollbackErr != nil {
+				return errors.Join(err, &RollbackError{ID: txn.id, Err: rollbackErr})
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// runCommitHooks runs the hooks registered with OnCommit.
+func (txn *TxNode) runCommitHooks() {
+	ctx := txn.hookContext()
+	for _, fn := range txn.onCommit {
+		fn(ctx)
+	}
+}
+
+// hookContext returns the context passed to hooks.
+func (txn *TxNode) hookContext() context.Context {
+	if txn.ctx == nil {
+		return context.Background()
+	}
+	return context.WithoutCancel(txn.ctx)
+}
+
+// OnRollback registers fn to run after the transaction has been rolled back,
+// whether explicitly, by a failed or vetoed commit, by the watchdog or the
+// maximum duration timer, or by panic recovery. fn receives the error that
+// triggered the rollback, which is nil for a plain RollbackTransaction call.
+// Hooks registered on a Nested child run when the child's savepoint is rolled
+// back, or are handed to its parent when the savepoint is released.
+// Hooks run on the goroutine that performs the rollback and are discarded once
+// the transaction has been committed. OnRollback has no effect if txn is nil.
+func (txn *TxNode) OnRollback(fn func(reason error)) {
+	if txn == nil {
+		return
+	}
+
+	txn.mu.Lock()
+	defer txn.mu.Unlock()
+	txn.onRollback = append(txn.onRollback, fn)
+}
+
+// runRollbackHooks runs the hooks registered with OnRollback, at most once.
+func (txn *TxNode) runRollbackHooks(reason error) {
+	for _, fn := range txn.takeRollbackHooks() {
+		fn(reason)
+	}
+}
+
+// takeRollbackHooks removes the hooks registered with OnRollback and returns them.
+func (txn *TxNode) takeRollbackHooks() []func(reason error) {
+	txn.mu.Lock()
+	defer txn.mu.Unlock()
+
+	hooks := txn.onRollback
+	txn.onRollback = nil
+	return hooks
+}
+
+// handOverRollbackHooks moves the rollback hooks of a Nested child to its parent.
+func (txn *TxNode) handOverRollbackHooks() {
+	hooks := txn.takeRollbackHooks()
+
+	txn.parent.mu.Lock()
+	defer txn.parent.mu.Unlock()
+	txn.parent.onRollback = append(txn.parent.onRollback, hooks...)
+}
@@ -0,0 +1,51 @@
+package txnode
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+)
+
+// ID returns the identifier of the node's transaction, a random UUID
+// generated when the transaction begins. It is included in the node's logs,
+// traces and errors to correlate all statements of one chain. Nested children
+// report the ID of the transaction they share. ID returns "" if no transaction
+// has been begun.
+func (txn *TxNode) ID() string {
+	if txn == nil {
+		return ""
+	}
+	return txn.root().id
+}
+
+// newID returns a random version 4 UUID.
+func newID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+
+	var s [36]byte
+	hex.Encode(s[0:8], b[0:4])
+	s[8] = '-'
+	hex.Encode(s[9:13], b[4:6])
+	s[13] = '-'
+	hex.Encode(s[14:18], b[6:8])
+	s[18] = '-'
+	hex.Encode(s[19:23], b[8:10])
+	s[23] = '-'
+	hex.Encode(s[24:], b[10:])
+	return string(s[:])
+}
+
+// wrapErr annotates err with the ID of the node's transaction.
+func (txn *TxNode) wrapErr(err error) error {
+	if err == nil {
+		return nil
+	}
+	id := txn.ID()
+	if id == "" {
+		return err
+	}
+	return fmt.Errorf("tx %s: %w", id, err)
+}
@@ -0,0 +1,106 @@
+package txnode
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// ErrEmptyIn is returned by In for an empty slice argument, which cannot be
+// expanded into a valid IN list.
+var ErrEmptyIn = errors.New("empty slice for IN list")
+
+// In expands the slice arguments of query, whose parameters are written as ?,
+// into one placeholder per element, and returns the query with the bind
+// placeholders of dialect d and the flattened arguments:
+//
+//	q, args, err := txnode.In(txnode.DialectPostgres,
+//		"SELECT * FROM users WHERE id IN (?) AND active = ?", ids, true)
+//
+// gives "... WHERE id IN ($1, $2, $3) AND active = $4" for three ids. Byte
+// slices and values implementing driver.Valuer are bound as single arguments.
+// Question marks inside string literals, quoted identifiers and comments are
+// not parameters.
+func In(d Dialect, query string, args ...any) (string, []any, error) {
+	return expandIn(d.bindvar(), query, args)
+}
+
+// In expands the slice arguments of query like the function In, for the
+// node's placeholder style, see WithRebind.
+func (txn *TxNode) In(query string, args ...any) (string, []any, error) {
+	return expandIn(txn.bindvar(), query, args)
+}
+
+// expandIn expands the slice arguments of query like In, writing placeholders
+// of style bv.
+func expandIn(bv Bindvar, query string, args []any) (string, []any, error) {
+	var (
+		b    strings.Builder
+		out  = make([]any, 0, len(args))
+		next int
+	)
+	b.Grow(len(query))
+	for i := 0; i < len(query); {
+		if end := skipLiteral(quoting{}, query, i); end > i {
+			b.WriteString(query[i:end])
+			i = end
+			continue
+		}
+		if query[i] != '?' {
+			b.WriteByte(query[i])
+			i++
+			continue
+		}
+		i++
+
+		if next >= len(args) {
+			return "", nil, fmt.Errorf("txnode: query has more placeholders than the %d arguments", len(args))
+		}
+		arg := args[next]
+		next++
+
+		elems, ok := expandable(arg)
+		if !ok {
+			out = append(out, arg)
+			b.WriteString(bv.placeholder(len(out)))
+			continue
+		}
+		if elems.Len() == 0 {
+			return "", nil, ErrEmptyIn
+		}
+		for j := 0; j < elems.Len(); j++ {
+			if j > 0 {
+				b.WriteString(", ")
+			}
+			out = append(out, elems.Index(j).Interface())
+			b.WriteString(bv.placeholder(len(out)))
+		}
+	}
+	if next != len(args) {
+		return "", nil, fmt.Errorf("txnode: query has %d placeholders for %d arguments", next, len(args))
+	}
+	return b.String(), out, nil
+}
+
+// expandable returns arg as a slice or array value if In expands it.
+func expandable(arg any) (reflect.Value, bool) {
+	if arg == nil {
+		return reflect.Value{}, false
+	}
+	if _, ok := arg.(driver.Valuer); ok {
+		return reflect.Value{}, false
+	}
+
+	rv := reflect.ValueOf(arg)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			return reflect.Value{}, false
+		}
+		return rv, true
+	default:
+		return reflect.Value{}, false
+	}
+}
@@ -0,0 +1,73 @@
+package txnode_test
+
+import (
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/MartellOnell/txnode"
+)
+
+func TestIn(t *testing.T) {
+	tests := []struct {
+		name     string
+		d        txnode.Dialect
+		query    string
+		args     []any
+		want     string
+		wantArgs []any
+	}{
+		{
+			"postgres", txnode.DialectPostgres,
+			"SELECT * FROM users WHERE id IN (?) AND active = ?", []any{[]int{1, 2, 3}, true},
+			"SELECT * FROM users WHERE id IN ($1, $2, $3) AND active = $4", []any{1, 2, 3, true},
+		},
+		{
+			"mysql", txnode.DialectMySQL,
+			"SELECT * FROM users WHERE id IN (?)", []any{[]string{"a", "b"}},
+			"SELECT * FROM users WHERE id IN (?, ?)", []any{"a", "b"},
+		},
+		{
+			"array", txnode.DialectSQLServer,
+			"SELECT * FROM t WHERE a = ? AND id IN (?)", []any{0, [2]int64{4, 5}},
+			"SELECT * FROM t WHERE a = @p1 AND id IN (@p2, @p3)", []any{0, int64(4), int64(5)},
+		},
+		{
+			"bytes and valuers", txnode.DialectPostgres,
+			"UPDATE t SET b = ? WHERE n = ?", []any{[]byte("x"), sql.NullInt64{Int64: 1, Valid: true}},
+			"UPDATE t SET b = $1 WHERE n = $2", []any{[]byte("x"), sql.NullInt64{Int64: 1, Valid: true}},
+		},
+		{
+			"literal", txnode.DialectPostgres,
+			"SELECT '?' FROM t WHERE id IN (?) -- ?", []any{[]int{1}},
+			"SELECT '?' FROM t WHERE id IN ($1) -- ?", []any{1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, args, err := txnode.In(tt.d, tt.query, tt.args...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("In() query = %q, want %q", got, tt.want)
+			}
+			if !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("In() args = %v, want %v", args, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestInErrors(t *testing.T) {
+	if _, _, err := txnode.In(txnode.DialectPostgres, "SELECT * FROM t WHERE id IN (?)", []int{}); !errors.Is(err, txnode.ErrEmptyIn) {
+		t.Errorf("In() with an empty slice = %v, want ErrEmptyIn", err)
+	}
+	if _, _, err := txnode.In(txnode.DialectPostgres, "SELECT ?, ?", 1); err == nil {
+		t.Error("In() with too few arguments succeeded")
+	}
+	if _, _, err := txnode.In(txnode.DialectPostgres, "SELECT ?", 1, 2); err == nil {
+		t.Error("In() with too many arguments succeeded")
+	}
+}
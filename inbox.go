@@ -0,0 +1,109 @@
+package txnode
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrAlreadyProcessed is returned by MarkProcessed for a message that has
+// been recorded before.
+var ErrAlreadyProcessed = errors.New("message already processed")
+
+// InboxSchema is the layout of the inbox table, as names of the table and of
+// its columns. MessageID must be the primary key or carry a unique index.
+type InboxSchema struct {
+	Table       string
+	MessageID   string
+	ProcessedAt string
+}
+
+// DefaultInboxSchema is the layout used when none is given:
+//
+//	CREATE TABLE txnode_inbox (
+//		message_id   varchar(255) PRIMARY KEY,
+//		processed_at timestamptz NOT NULL
+//	)
+var DefaultInboxSchema = InboxSchema{
+	Table:       "txnode_inbox",
+	MessageID:   "message_id",
+	ProcessedAt: "processed_at",
+}
+
+// Inbox records the IDs of processed messages in the transaction of a node,
+// so that a consumer processes every message exactly once: the record is
+// committed together with the effects of processing the message, and a
+// redelivered message is detected as a duplicate.
+type Inbox struct {
+	schema InboxSchema
+}
+
+// NewInbox returns an inbox recording into the table described by schema.
+func NewInbox(schema InboxSchema) (*Inbox, error) {
+	if !isQualifiedIdentifier(schema.Table) {
+		return nil, fmt.Errorf("txnode: invalid inbox table %q", schema.Table)
+	}
+	for _, col := range []string{schema.MessageID, schema.ProcessedAt} {
+		if !isIdentifier(col) {
+			return nil, fmt.Errorf("txnode: invalid inbox column %q", col)
+		}
+	}
+	return &Inbox{schema: schema}, nil
+}
+
+// WithInbox attaches i to the node, see MarkProcessed.
+func WithInbox(i *Inbox) Option {
+	return func(c *config) {
+		c.inbox = i
+	}
+}
+
+// MarkProcessed records messageID in the inbox attached with WithInbox, in the
+// node's transaction, beginning it on db if needed. It returns
+// ErrAlreadyProcessed if the message has been recorded before, in which case
+// the consumer should roll back and acknowledge the message. Call it before
+// doing the work, so that concurrent deliveries of the same message wait for
+// each other on the inbox row.
+func (txn *TxNode) MarkProcessed(ctx context.Context, db DB, messageID string) error {
+	if txn == nil {
+		return fmt.Errorf("txnode: mark processed: %w", ErrNotStarted)
+	}
+	if txn.cfg.inbox == nil {
+		return fmt.Errorf("txnode: mark processed: no inbox, see WithInbox")
+	}
+
+	d := txn.cfg.dialect
+	res, err := txn.ExecContext(ctx, db, txn.cfg.inbox.insertSQL(d), messageID, time.Now().UTC())
+	if err != nil {
+		if d == DialectSQLServer && txn.cfg.classifier.Classify(err) == CategoryConstraint {
+			return fmt.Errorf("txnode: %w: %s", ErrAlreadyProcessed, messageID)
+		}
+		return fmt.Errorf("txnode: mark processed %s: %w", messageID, err)
+	}
+
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("txnode: %w: %s", ErrAlreadyProcessed, messageID)
+	}
+	return nil
+}
+
+// insertSQL returns the statement recording a message in dialect d. It
+// inserts no row, instead of failing, for a duplicate message where the
+// dialect allows it, so that the transaction stays usable.
+func (i *Inbox) insertSQL(d Dialect) string {
+	s := i.schema
+	cols := " (" + s.MessageID + ", " + s.ProcessedAt + ") VALUES (" +
+		d.placeholder(1) + ", " + d.placeholder(2) + ")"
+
+	switch d {
+	case DialectPostgres:
+		return "INSERT INTO " + s.Table + cols + " ON CONFLICT (" + s.MessageID + ") DO NOTHING"
+	case DialectMySQL:
+		return "INSERT IGNORE INTO " + s.Table + cols
+	case DialectSQLite:
+		return "INSERT OR IGNORE INTO " + s.Table + cols
+	default:
+		return "INSERT INTO " + s.Table + cols
+	}
+}
@@ -0,0 +1,43 @@
+package txnode
+
+import (
+	"context"
+	"slices"
+)
+
+// Invalidator evicts cache entries by key. It is called with the keys touched
+// by a transaction once that transaction has been committed.
+type Invalidator func(ctx context.Context, keys []string)
+
+// WithInvalidator sets the function called with the keys collected by
+// InvalidateKey after a successful commit.
+func WithInvalidator(fn Invalidator) Option {
+	return func(c *config) {
+		c.invalidator = fn
+	}
+}
+
+// InvalidateKey records a cache key touched by the chain. The keys recorded on
+// a node are handed to the invalidator set with WithInvalidator once, after the
+// transaction has been committed, and dropped if it is rolled back; keys
+// recorded on a Nested child are dropped if its savepoint is rolled back.
+// InvalidateKey does nothing if txn is nil, since no transaction defers the
+// writes then, or if no invalidator is set.
+func (txn *TxNode) InvalidateKey(keys ...string) {
+	if txn == nil || txn.cfg.invalidator == nil || len(keys) == 0 {
+		return
+	}
+
+	if txn.invalidate == nil {
+		txn.OnCommit(func(ctx context.Context) {
+			keys := txn.invalidate
+			txn.invalidate = nil
+			txn.cfg.invalidator(ctx, keys)
+		})
+	}
+	for _, k := range keys {
+		if !slices.Contains(txn.invalidate, k) {
+			txn.invalidate = append(txn.invalidate, k)
+		}
+	}
+}
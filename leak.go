@@ -0,0 +1,124 @@
+package txnode
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// maxLeakFrames bounds the call stack recorded for leak warnings.
+const maxLeakFrames = 32
+
+// WithLeakDetection enables or disables the detection of leaked transactions.
+// When enabled, a node that is garbage collected while its transaction is
+// still open logs an error with the call stack that began the transaction,
+// and the transaction is rolled back to return its connection to the pool.
+// Leaks are logged to slog.Default if the node has no logger, since they
+// are bugs that should not go unnoticed. Detection is enabled by default.
+func WithLeakDetection(enabled bool) Option {
+	return func(c *config) {
+		c.noLeakCheck = !enabled
+	}
+}
+
+// leakToken holds what the cleanup of a leaked node needs. It must not refer
+// to the node itself, which would keep the node reachable.
+type leakToken struct {
+	ended atomic.Bool
+	tx    *sql.Tx
+	// conn is the connection the transaction is begun on, if the node
+	// holds one.
+	conn  *sql.Conn
+	id    string
+	began time.Time
+	pcs   []uintptr
+	debug *debugInfo
+	log   Logger
+	// release releases the slot taken for WithMaxConcurrentTx, if any.
+	release func()
+}
+
+// watchLeak registers the leak check for the node's transaction.
+func (txn *TxNode) watchLeak() {
+	if txn.cfg.noLeakCheck {
+		return
+	}
+
+	log := txn.cfg.logger
+	if log == nil {
+		log = NewSlogLogger(slog.Default())
+	}
+
+	pcs := make([]uintptr, maxLeakFrames)
+	pcs = pcs[:runtime.Callers(3, pcs)]
+
+	token := &leakToken{
+		tx:      txn.tx,
+		conn:    txn.conn,
+		id:      txn.id,
+		began:   txn.began,
+		pcs:     pcs,
+		debug:   txn.debug,
+		log:     log,
+		release: txn.releaseSlot,
+	}
+	txn.leak = token
+	runtime.AddCleanup(txn, reportLeak, token)
+}
+
+// endLeak marks the node's transaction as ended, disarming the leak check.
+func (txn *TxNode) endLeak() {
+	if txn.leak != nil {
+		txn.leak.ended.Store(true)
+	}
+}
+
+// reportLeak runs once a node has been garbage collected.
+func reportLeak(token *leakToken) {
+	if token.ended.Load() {
+		return
+	}
+
+	attrs := []slog.Attr{
+		slog.String("tx_id", token.id),
+		slog.Duration("age", time.Since(token.began)),
+	}
+	if token.debug != nil {
+		attrs = append(attrs, token.debug.attrs()...)
+	} else {
+		attrs = append(attrs, slog.String("begin_stack", formatStack(token.pcs)))
+	}
+	token.log.Log(context.Background(), slog.LevelError, "transaction leaked", attrs...)
+	_ = token.tx.Rollback()
+	if token.conn != nil {
+		// The session settings of the transaction were not restored.
+		discardConn(token.conn)
+	}
+	if token.release != nil {
+		token.release()
+	}
+}
+
+// formatStack renders the call stack pcs one frame per line.
+func formatStack(pcs []uintptr) string {
+	var b strings.Builder
+	frames := runtime.CallersFrames(pcs)
+	for {
+		f, more := frames.Next()
+		b.WriteString(f.Function)
+		b.WriteString("\n\t")
+		b.WriteString(f.File)
+		b.WriteByte(':')
+		b.WriteString(strconv.Itoa(f.Line))
+		b.WriteByte('\n')
+		if !more {
+			break
+		}
+	}
+	return b.String()
+}
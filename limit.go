@@ -0,0 +1,60 @@
+package txnode
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrTooManyTx is returned when beginning a transaction while the limit set
+// with WithMaxConcurrentTxNoWait is reached.
+var ErrTooManyTx = errors.New("too many concurrent transactions")
+
+// WithMaxConcurrentTx limits the number of transactions open at the same time
+// to n. Beginning a transaction beyond the limit waits until another one ends
+// or the context is done. Like WithCircuitBreaker, the limit is shared by all
+// nodes created with the same Option value, such as all nodes of a Manager,
+// which keeps long chains from exhausting the connection pool under load.
+func WithMaxConcurrentTx(n int) Option {
+	return withLimiter(&txLimiter{slots: make(chan struct{}, max(n, 1)), wait: true})
+}
+
+// WithMaxConcurrentTxNoWait is like WithMaxConcurrentTx but beginning a
+// transaction beyond the limit fails at once with ErrTooManyTx.
+func WithMaxConcurrentTxNoWait(n int) Option {
+	return withLimiter(&txLimiter{slots: make(chan struct{}, max(n, 1))})
+}
+
+func withLimiter(l *txLimiter) Option {
+	return func(c *config) {
+		c.limiter = l
+	}
+}
+
+type txLimiter struct {
+	slots chan struct{}
+	wait  bool
+}
+
+// acquire takes a slot for a transaction and returns the function releasing
+// it. A nil limiter has unlimited slots.
+func (l *txLimiter) acquire(ctx context.Context) (func(), error) {
+	if l == nil {
+		return func() {}, nil
+	}
+
+	select {
+	case l.slots <- struct{}{}:
+		return func() { <-l.slots }, nil
+	default:
+	}
+	if !l.wait {
+		return nil, ErrTooManyTx
+	}
+
+	select {
+	case l.slots <- struct{}{}:
+		return func() { <-l.slots }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
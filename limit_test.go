@@ -0,0 +1,68 @@
+package txnode_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/MartellOnell/txnode"
+	"github.com/MartellOnell/txnode/txtest"
+)
+
+func TestMaxConcurrentTxNoWait(t *testing.T) {
+	db := txtest.NewMock().DB()
+	limit := txnode.WithMaxConcurrentTxNoWait(1)
+	ctx := context.Background()
+
+	first := txnode.New(limit)
+	if err := first.Begin(ctx, db); err != nil {
+		t.Fatal(err)
+	}
+	second := txnode.New(limit)
+	if err := second.Begin(ctx, db); !errors.Is(err, txnode.ErrTooManyTx) {
+		t.Fatalf("Begin() beyond the limit = %v, want ErrTooManyTx", err)
+	}
+
+	if err := first.RollbackTransaction(); err != nil {
+		t.Fatal(err)
+	}
+	if err := second.Begin(ctx, db); err != nil {
+		t.Fatalf("Begin() after a transaction ended = %v", err)
+	}
+	if err := second.RollbackTransaction(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMaxConcurrentTxWaits(t *testing.T) {
+	db := txtest.NewMock().DB()
+	limit := txnode.WithMaxConcurrentTx(1)
+
+	first := txnode.New(limit)
+	if err := first.Begin(context.Background(), db); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := txnode.New(limit).Begin(ctx, db); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Begin() waiting beyond the limit = %v, want context.DeadlineExceeded", err)
+	}
+
+	done := make(chan error, 1)
+	second := txnode.New(limit)
+	go func() {
+		done <- second.Begin(context.Background(), db)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	if err := first.RollbackTransaction(); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("Begin() waiting for a slot = %v", err)
+	}
+	if err := second.RollbackTransaction(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,147 @@
+package txnode
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// Logger receives the records the node logs. Implementations route them into
+// the application's logging stack; NewSlogLogger adapts a *slog.Logger, and
+// the zaplog and logruslog packages adapt zap and logrus.
+//
+// A Logger that also has an Enabled(ctx, level) bool method is asked first, so
+// that the node can skip building records that would be dropped.
+type Logger interface {
+	Log(ctx context.Context, level slog.Level, msg string, attrs ...slog.Attr)
+}
+
+// NewSlogLogger returns a Logger that writes to log.
+func NewSlogLogger(log *slog.Logger) Logger {
+	return slogLogger{log: log}
+}
+
+type slogLogger struct {
+	log *slog.Logger
+}
+
+func (l slogLogger) Log(ctx context.Context, level slog.Level, msg string, attrs ...slog.Attr) {
+	l.log.LogAttrs(ctx, level, msg, attrs...)
+}
+
+func (l slogLogger) Enabled(ctx context.Context, level slog.Level) bool {
+	return l.log.Enabled(ctx, level)
+}
+
+// nopLogger is used by nodes configured without a logger.
+type nopLogger struct{}
+
+func (nopLogger) Log(context.Context, slog.Level, string, ...slog.Attr) {}
+
+func (nopLogger) Enabled(context.Context, slog.Level) bool { return false }
+
+// enabled reports whether log wants records at level.
+func enabled(ctx context.Context, log Logger, level slog.Level) bool {
+	if e, ok := log.(interface {
+		Enabled(ctx context.Context, level slog.Level) bool
+	}); ok {
+		return e.Enabled(ctx, level)
+	}
+	return true
+}
+
+// logger returns the node's logger. It is safe to call on a nil node.
+func (txn *TxNode) logger() Logger {
+	if txn == nil {
+		return nopLogger{}
+	}
+	return txn.cfg.log()
+}
+
+// logContext returns the context for log records about the transaction.
+// It is safe to call on a nil node.
+func (txn *TxNode) logContext() context.Context {
+	if txn == nil {
+		return context.Background()
+	}
+	return txn.root().hookContext()
+}
+
+// logAttrs returns attrs followed by the attributes describing the node's
+// transaction. It is safe to call on a nil node.
+func (txn *TxNode) logAttrs(attrs ...slog.Attr) []slog.Attr {
+	if txn == nil {
+		return attrs
+	}
+
+	if id := txn.ID(); id != "" {
+		attrs = append(attrs, slog.String("tx_id", id))
+	}
+	if st := txn.Stats(); st.Duration > 0 {
+		attrs = append(attrs, slog.Duration("duration", st.Duration))
+	}
+	return attrs
+}
+
+// logDebug logs a debug record about the transaction.
+func (txn *TxNode) logDebug(msg string, attrs ...slog.Attr) {
+	txn.log(slog.LevelDebug, msg, attrs...)
+}
+
+// log logs a record about the transaction at level.
+func (txn *TxNode) log(level slog.Level, msg string, attrs ...slog.Attr) {
+	txn.logTo(txn.logger(), level, msg, attrs...)
+}
+
+// logTo logs a record about the transaction at level to log.
+func (txn *TxNode) logTo(log Logger, level slog.Level, msg string, attrs ...slog.Attr) {
+	ctx := txn.logContext()
+	if !enabled(ctx, log, level) {
+		return
+	}
+	log.Log(ctx, level, msg, txn.logAttrs(attrs...)...)
+}
+
+// logBegin logs beginning the transaction at debug level.
+func (txn *TxNode) logBegin(ctx context.Context, start time.Time, err error) {
+	log := txn.logger()
+	if !enabled(ctx, log, slog.LevelDebug) {
+		return
+	}
+
+	attrs := []slog.Attr{
+		slog.String("tx_id", txn.id),
+		slog.Duration("begin_duration", time.Since(start)),
+	}
+	if err != nil {
+		attrs = append(attrs, slog.Any("error", err))
+	}
+	log.Log(ctx, slog.LevelDebug, "begin transaction", attrs...)
+}
+
+var _ slog.LogValuer = (*TxNode)(nil)
+
+// LogValue implements slog.LogValuer, so that slog.Any("tx", txn) logs the
+// state, ID, age and statement count of the node's transaction as a group.
+func (txn *TxNode) LogValue() slog.Value {
+	if txn == nil {
+		return slog.GroupValue(slog.String("state", "none"))
+	}
+
+	st := txn.Stats()
+	attrs := []slog.Attr{slog.String("state", txn.State().String())}
+	if txn.Err() != nil {
+		attrs = append(attrs, slog.Bool("aborted", true))
+	}
+	if id := txn.ID(); id != "" {
+		attrs = append(attrs, slog.String("id", id))
+	}
+	attrs = append(attrs,
+		slog.Duration("age", st.Duration),
+		slog.Int("statements", st.Statements),
+	)
+	if txn.savepoint != "" {
+		attrs = append(attrs, slog.String("savepoint", txn.savepoint))
+	}
+	return slog.GroupValue(attrs...)
+}
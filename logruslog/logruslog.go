@@ -0,0 +1,85 @@
+// Package logruslog routes txnode logs into a logrus logger.
+//
+//	txn := txnode.New(logruslog.WithLogger(logrus.StandardLogger()))
+package logruslog
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/MartellOnell/txnode"
+)
+
+// Logger is a txnode.Logger that writes to a logrus logger.
+type Logger struct {
+	log logrus.FieldLogger
+}
+
+var _ txnode.Logger = (*Logger)(nil)
+
+// New returns a Logger that writes to log, which is typically a
+// *logrus.Logger or a *logrus.Entry carrying fields of its own.
+func New(log logrus.FieldLogger) *Logger {
+	return &Logger{log: log}
+}
+
+// WithLogger returns a txnode option that makes the node log to log.
+func WithLogger(log logrus.FieldLogger) txnode.Option {
+	return txnode.WithCustomLogger(New(log))
+}
+
+// Log implements txnode.Logger. Attributes in groups are flattened into
+// fields named "group.key".
+func (l *Logger) Log(ctx context.Context, level slog.Level, msg string, attrs ...slog.Attr) {
+	f := make(logrus.Fields, len(attrs))
+	addFields(f, "", attrs)
+	l.log.WithFields(f).WithContext(ctx).Log(logrusLevel(level), msg)
+}
+
+// Enabled reports whether the logrus logger records entries at level.
+func (l *Logger) Enabled(_ context.Context, level slog.Level) bool {
+	var logger *logrus.Logger
+	switch log := l.log.(type) {
+	case *logrus.Logger:
+		logger = log
+	case *logrus.Entry:
+		logger = log.Logger
+	default:
+		return true
+	}
+	return logger.IsLevelEnabled(logrusLevel(level))
+}
+
+// logrusLevel maps a slog level to the closest logrus level.
+func logrusLevel(level slog.Level) logrus.Level {
+	switch {
+	case level < slog.LevelInfo:
+		return logrus.DebugLevel
+	case level < slog.LevelWarn:
+		return logrus.InfoLevel
+	case level < slog.LevelError:
+		return logrus.WarnLevel
+	default:
+		return logrus.ErrorLevel
+	}
+}
+
+func addFields(f logrus.Fields, prefix string, attrs []slog.Attr) {
+	for _, a := range attrs {
+		v := a.Value.Resolve()
+		key := prefix + a.Key
+		if v.Kind() == slog.KindGroup {
+			if a.Key != "" {
+				key += "."
+			}
+			addFields(f, key, v.Group())
+			continue
+		}
+		if a.Key == "" {
+			continue
+		}
+		f[key] = v.Any()
+	}
+}
@@ -0,0 +1,159 @@
+package txnode
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+	"weak"
+)
+
+// ErrShutdown is returned when beginning a transaction on a node of a Manager
+// that is shutting down, and is the reason recorded on the transactions
+// rolled back by Shutdown.
+var ErrShutdown = errors.New("manager shut down")
+
+// Manager creates the nodes of an application on one database with a common
+// configuration. Options that hold shared state, such as WithCircuitBreaker,
+// apply to all nodes of the manager together. The manager keeps track of the
+// transactions of its nodes, see Shutdown.
+type Manager struct {
+	db   DB
+	opts []Option
+
+	mu sync.Mutex
+	// active holds weak pointers, so that a leaked node can still be
+	// garbage collected and reported, see WithLeakDetection.
+	active   map[weak.Pointer[TxNode]]struct{}
+	closed   bool
+	drained  chan struct{}
+	shutdown sync.Once
+}
+
+// NewManager returns a manager creating nodes on db with opts.
+func NewManager(db DB, opts ...Option) *Manager {
+	return &Manager{
+		db:      db,
+		opts:    opts,
+		active:  make(map[weak.Pointer[TxNode]]struct{}),
+		drained: make(chan struct{}),
+	}
+}
+
+// DB returns the database of the manager.
+func (m *Manager) DB() DB {
+	return m.db
+}
+
+// New returns a node configured with the options of the manager followed by
+// opts.
+func (m *Manager) New(opts ...Option) *TxNode {
+	return New(m.options(opts)...)
+}
+
+// Run runs fn in a transaction on the manager's database like the function
+// Run, with the options of the manager followed by opts.
+func (m *Manager) Run(ctx context.Context, fn func(ctx context.Context, txn *TxNode) error, opts ...Option) error {
+	return Run(ctx, m.db, fn, m.options(opts)...)
+}
+
+// Shutdown stops the manager from beginning new transactions, which fail with
+// ErrShutdown, and waits for the open transactions of its nodes to end. If ctx
+// is done first, the remaining transactions are rolled back, which aborts
+// their nodes with an error wrapping ErrAborted and ErrShutdown, and Shutdown
+// returns an error wrapping the context's error.
+func (m *Manager) Shutdown(ctx context.Context) error {
+	m.mu.Lock()
+	m.closed = true
+	m.checkDrained()
+	m.mu.Unlock()
+
+	select {
+	case <-m.drained:
+		return nil
+	case <-ctx.Done():
+	}
+
+	m.mu.Lock()
+	nodes := make([]*TxNode, 0, len(m.active))
+	for p := range m.active {
+		if txn := p.Value(); txn != nil {
+			nodes = append(nodes, txn)
+		}
+	}
+	m.mu.Unlock()
+
+	for _, txn := range nodes {
+		txn.abort(fmt.Errorf("%w: %w", ErrAborted, ErrShutdown))
+	}
+	return fmt.Errorf("txnode: shutdown: rolled back %d transactions: %w", len(nodes), ctx.Err())
+}
+
+// options returns the options of the manager followed by opts.
+func (m *Manager) options(opts []Option) []Option {
+	opts = append(m.opts[:len(m.opts):len(m.opts)], opts...)
+	return append(opts, func(c *config) {
+		c.manager = m
+	})
+}
+
+// admit returns ErrShutdown if the manager is shutting down. A nil manager
+// admits every transaction.
+func (m *Manager) admit() error {
+	if m == nil {
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return ErrShutdown
+	}
+	return nil
+}
+
+// track records the open transaction of txn. It reports false if the manager
+// is shutting down. A node garbage collected before its transaction ended is
+// forgotten by its cleanup.
+func (m *Manager) track(txn *TxNode) bool {
+	if m == nil {
+		return true
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return false
+	}
+	p := weak.Make(txn)
+	m.active[p] = struct{}{}
+	runtime.AddCleanup(txn, m.forget, p)
+	return true
+}
+
+// untrack forgets the transaction of txn once it has ended.
+func (m *Manager) untrack(txn *TxNode) {
+	if m == nil {
+		return
+	}
+	m.forget(weak.Make(txn))
+}
+
+// forget removes the transaction p from the open ones.
+func (m *Manager) forget(p weak.Pointer[TxNode]) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.active, p)
+	m.checkDrained()
+}
+
+// checkDrained signals Shutdown once no transaction is left. m.mu must be held.
+func (m *Manager) checkDrained() {
+	if m.closed && len(m.active) == 0 {
+		m.shutdown.Do(func() { close(m.drained) })
+	}
+}
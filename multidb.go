@@ -0,0 +1,177 @@
+package txnode
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"maps"
+)
+
+// WithDatabase registers db under name, so that a chain can address several
+// databases while keeping the single-node API. Statements sent to name with
+// the On methods, such as PrepareQueryOn, run in a transaction of their own on
+// db, begun lazily and configured like the node, except that they take no
+// slot of WithMaxConcurrentTx and are not subject to WithCircuitBreaker,
+// which concern the node's own database. Those transactions end with the
+// node's: CommitIfNeeded commits them after the node's own transaction, and
+// they are rolled back otherwise. Committing several databases is best effort,
+// see Outcomes.
+func WithDatabase(name string, db DB) Option {
+	return func(c *config) {
+		dbs := maps.Clone(c.databases)
+		if dbs == nil {
+			dbs = make(map[string]DB)
+		}
+		dbs[name] = db
+		c.databases = dbs
+	}
+}
+
+// Outcome is how the transaction on one database registered with WithDatabase
+// ended.
+type Outcome struct {
+	Database  string
+	Committed bool
+	Err       error
+}
+
+// DatabaseError reports the failure to end the transaction on a database
+// registered with WithDatabase.
+type DatabaseError struct {
+	Database string
+	Err      error
+}
+
+func (e *DatabaseError) Error() string {
+	return fmt.Sprintf("txnode: database %s: %v", e.Database, e.Err)
+}
+
+func (e *DatabaseError) Unwrap() error {
+	return e.Err
+}
+
+// namedTx is the transaction of a chain on a database registered with
+// WithDatabase.
+type namedTx struct {
+	name string
+	db   DB
+	txn  *TxNode
+	// err is the error the transaction ended with.
+	err error
+}
+
+// on returns the node and database registered under name, creating the node
+// on first use.
+func (txn *TxNode) on(name string) (*TxNode, DB, error) {
+	if txn == nil {
+		return nil, nil, fmt.Errorf("txnode: database %s: %w", name, ErrNotStarted)
+	}
+
+	root := txn.root()
+	db, ok := root.cfg.databases[name]
+	if !ok {
+		return nil, nil, fmt.Errorf("txnode: unknown database %q, see WithDatabase", name)
+	}
+	for _, n := range root.named {
+		if n.name == name {
+			return n.txn, n.db, nil
+		}
+	}
+
+	// The chain already holds its slot of WithMaxConcurrentTx, and the
+	// circuit breaker watches the node's own database.
+	cfg := root.cfg
+	cfg.databases = nil
+	cfg.limiter = nil
+	cfg.breaker = nil
+	n := &namedTx{name: name, db: db, txn: &TxNode{isStart: true, isEnd: true, cfg: cfg}}
+	root.named = append(root.named, n)
+	return n.txn, db, nil
+}
+
+// PrepareQueryOn prepares a statement on the database registered under name.
+func (txn *TxNode) PrepareQueryOn(ctx context.Context, name, query string) (*sql.Stmt, error) {
+	n, db, err := txn.on(name)
+	if err != nil {
+		return nil, err
+	}
+	return n.PrepareQuery(ctx, db, query)
+}
+
+// ExecOn executes a statement on the database registered under name.
+func (txn *TxNode) ExecOn(ctx context.Context, name, query string, args ...any) (sql.Result, error) {
+	n, db, err := txn.on(name)
+	if err != nil {
+		return nil, err
+	}
+	return n.ExecContext(ctx, db, query, args...)
+}
+
+// QueryOn runs a query on the database registered under name.
+func (txn *TxNode) QueryOn(ctx context.Context, name, query string, args ...any) (*sql.Rows, error) {
+	n, db, err := txn.on(name)
+	if err != nil {
+		return nil, err
+	}
+	return n.QueryContext(ctx, db, query, args...)
+}
+
+// QueryRowOn runs a query expected to return at most one row on the database
+// registered under name.
+func (txn *TxNode) QueryRowOn(ctx context.Context, name, query string, args ...any) *Row {
+	n, db, err := txn.on(name)
+	if err != nil {
+		return &Row{err: err}
+	}
+	return n.QueryRowContext(ctx, db, query, args...)
+}
+
+// Outcomes reports how the transactions on the databases registered with
+// WithDatabase ended, in the order they were begun. Databases the chain did
+// not use are not reported.
+func (txn *TxNode) Outcomes() []Outcome {
+	if txn == nil {
+		return nil
+	}
+
+	root := txn.root()
+	out := make([]Outcome, 0, len(root.named))
+	for _, n := range root.named {
+		if n.txn.State() == StateIdle {
+			continue
+		}
+		out = append(out, Outcome{
+			Database:  n.name,
+			Committed: n.txn.State() == StateCommitted,
+			Err:       n.err,
+		})
+	}
+	return out
+}
+
+// commitDatabases commits the transactions on the registered databases and
+// returns the DatabaseErrors of those that failed.
+func (txn *TxNode) commitDatabases() error {
+	var errs []error
+	for _, n := range txn.named {
+		if !n.txn.IsActive() {
+			continue
+		}
+		if err := n.txn.CommitIfNeeded(); err != nil {
+			n.err = err
+			errs = append(errs, &DatabaseError{Database: n.name, Err: err})
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// rollbackDatabases rolls back the transactions on the registered databases
+// that are still active.
+func (txn *TxNode) rollbackDatabases() {
+	for _, n := range txn.named {
+		if n.txn.IsActive() {
+			n.err = n.txn.RollbackTransaction()
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package txnode
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// NamedExec runs query like ExecContext after binding its :name parameters
+// from arg for the node's placeholder style, see BindNamed and WithRebind.
+func (txn *TxNode) NamedExec(ctx context.Context, db DB, query string, arg any) (sql.Result, error) {
+	q, args, err := bindNamed(txn.bindvar(), query, arg)
+	if err != nil {
+		return nil, err
+	}
+	return txn.ExecContext(ctx, db, q, args...)
+}
+
+// NamedQuery runs query like QueryContext after binding its :name parameters
+// from arg for the node's placeholder style, see BindNamed and WithRebind.
+func (txn *TxNode) NamedQuery(ctx context.Context, db DB, query string, arg any) (*sql.Rows, error) {
+	q, args, err := bindNamed(txn.bindvar(), query, arg)
+	if err != nil {
+		return nil, err
+	}
+	return txn.QueryContext(ctx, db, q, args...)
+}
+
+// BindNamed replaces the :name parameters of query with the bind placeholders
+// of dialect d and returns the arguments in placeholder order. The values are
+// taken from arg, which is a map[string]any keyed by name or a struct, or a
+// pointer to one, whose fields are matched to the names as ScanRow matches
+// columns. Parameters inside string literals, quoted identifiers and comments
+// are left alone, as are Postgres casts such as ::text. A name used several
+// times binds a single argument in dialects with numbered placeholders.
+func BindNamed(d Dialect, query string, arg any) (string, []any, error) {
+	return bindNamed(d.bindvar(), query, arg)
+}
+
+// bindNamed binds the :name parameters of query like BindNamed, writing
+// placeholders of style bv.
+func bindNamed(bv Bindvar, query string, arg any) (string, []any, error) {
+	lookup, err := namedLookup(arg)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var (
+		b    strings.Builder
+		args []any
+		seen map[string]int
+	)
+	b.Grow(len(query))
+	for i := 0; i < len(query); {
+		if end := skipLiteral(quoting{}, query, i); end > i {
+			b.WriteString(query[i:end])
+			i = end
+			continue
+		}
+
+		c := query[i]
+		if c == ':' && i+1 < len(query) && query[i+1] == ':' {
+			b.WriteString("::")
+			i += 2
+			continue
+		}
+		if c != ':' || i+1 >= len(query) || !isIdentByte(query[i+1]) || isDigit(query[i+1]) {
+			b.WriteByte(c)
+			i++
+			continue
+		}
+
+		j := i + 1
+		for j < len(query) && (isIdentByte(query[j]) || query[j] == '.') {
+			j++
+		}
+		name := query[i+1 : j]
+		i = j
+
+		if n, ok := seen[name]; ok && bv.numbered() {
+			b.WriteString(bv.placeholder(n))
+			continue
+		}
+		v, ok := lookup(name)
+		if !ok {
+			return "", nil, fmt.Errorf("txnode: no value for named parameter %q", name)
+		}
+		args = append(args, v)
+		if seen == nil {
+			seen = make(map[string]int)
+		}
+		seen[name] = len(args)
+		b.WriteString(bv.placeholder(len(args)))
+	}
+	return b.String(), args, nil
+}
+
+// namedLookup returns a function looking up the values of named parameters
+// in arg.
+func namedLookup(arg any) (func(name string) (any, bool), error) {
+	if m, ok := arg.(map[string]any); ok {
+		return func(name string) (any, bool) {
+			v, ok := m[name]
+			return v, ok
+		}, nil
+	}
+
+	rv := reflect.ValueOf(arg)
+	for rv.Kind() == reflect.Pointer && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("txnode: named parameters from %T", arg)
+	}
+
+	fields := structMapOf(rv.Type())
+	return func(name string) (any, bool) {
+		path, ok := fields[strings.ToLower(name)]
+		if !ok {
+			return nil, false
+		}
+		f, err := rv.FieldByIndexErr(path)
+		if err != nil {
+			// The field is in a nil embedded struct.
+			return nil, true
+		}
+		return f.Interface(), true
+	}, nil
+}
+
+// skipLiteral returns the index just past the string literal, quoted
+// identifier or comment starting at i, read with the syntax q, or i if there
+// is none.
+func skipLiteral(q quoting, query string, i int) int {
+	switch c := query[i]; {
+	case c == '\'' || c == '"':
+		return skipString(query, i, c, q.backslash)
+	case c == '`':
+		return skipQuoted(query, i, c)
+	case (c == 'E' || c == 'e') && isStringPrefix(query, i):
+		return skipString(query, i+1, '\'', true)
+	case c == '$' && i+1 < len(query) && !isDigit(query[i+1]):
+		if end, ok := skipDollarQuoted(query, i); ok {
+			return end
+		}
+	case isCommentStart(query, i, q):
+		return skipComment(query, i)
+	}
+	return i
+}
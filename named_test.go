@@ -0,0 +1,101 @@
+package txnode_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/MartellOnell/txnode"
+)
+
+type namedUser struct {
+	ID    int64
+	Name  string `db:"user_name"`
+	Email string `db:"-"`
+}
+
+func TestBindNamed(t *testing.T) {
+	tests := []struct {
+		name     string
+		d        txnode.Dialect
+		query    string
+		arg      any
+		want     string
+		wantArgs []any
+	}{
+		{
+			"map postgres", txnode.DialectPostgres,
+			"UPDATE t SET a = :a WHERE id = :id",
+			map[string]any{"a": 1, "id": 2},
+			"UPDATE t SET a = $1 WHERE id = $2", []any{1, 2},
+		},
+		{
+			"map mysql", txnode.DialectMySQL,
+			"UPDATE t SET a = :a WHERE id = :id",
+			map[string]any{"a": 1, "id": 2},
+			"UPDATE t SET a = ? WHERE id = ?", []any{1, 2},
+		},
+		{
+			"repeated name numbered", txnode.DialectPostgres,
+			"SELECT * FROM t WHERE a = :v OR b = :v",
+			map[string]any{"v": "x"},
+			"SELECT * FROM t WHERE a = $1 OR b = $1", []any{"x"},
+		},
+		{
+			"repeated name question", txnode.DialectSQLite,
+			"SELECT * FROM t WHERE a = :v OR b = :v",
+			map[string]any{"v": "x"},
+			"SELECT * FROM t WHERE a = ? OR b = ?", []any{"x", "x"},
+		},
+		{
+			"struct", txnode.DialectSQLServer,
+			"INSERT INTO users (id, name) VALUES (:id, :user_name)",
+			namedUser{ID: 7, Name: "ann"},
+			"INSERT INTO users (id, name) VALUES (@p1, @p2)", []any{int64(7), "ann"},
+		},
+		{
+			"struct pointer", txnode.DialectPostgres,
+			"SELECT * FROM users WHERE id = :ID",
+			&namedUser{ID: 7},
+			"SELECT * FROM users WHERE id = $1", []any{int64(7)},
+		},
+		{
+			"cast and literals", txnode.DialectPostgres,
+			"SELECT :a::text, ':b', \":c\" /* :d */ FROM t -- :e",
+			map[string]any{"a": 1},
+			"SELECT $1::text, ':b', \":c\" /* :d */ FROM t -- :e", []any{1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, args, err := txnode.BindNamed(tt.d, tt.query, tt.arg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("BindNamed() query = %q, want %q", got, tt.want)
+			}
+			if !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("BindNamed() args = %v, want %v", args, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestBindNamedErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		arg   any
+	}{
+		{"missing key", "SELECT :a", map[string]any{"b": 1}},
+		{"skipped field", "SELECT :email", namedUser{Email: "a@b"}},
+		{"not a struct", "SELECT :a", 42},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := txnode.BindNamed(txnode.DialectPostgres, tt.query, tt.arg); err == nil {
+				t.Errorf("BindNamed(%q, %v) succeeded, want an error", tt.query, tt.arg)
+			}
+		})
+	}
+}
@@ -0,0 +1,230 @@
+package txnode
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// Observer receives the lifecycle events of the transactions of the nodes it
+// is attached to with WithObserver. It is the single extension point for
+// metrics, tracing and auditing. Methods are called synchronously on the
+// goroutine that caused the event and must not block.
+type Observer interface {
+	// TxBegan is called after beginning a transaction, successfully or not.
+	TxBegan(ctx context.Context, ev BeginEvent)
+	// StmtPrepared is called after a statement was prepared through PrepareQuery.
+	StmtPrepared(ctx context.Context, ev StmtEvent)
+	// StmtExecuted is called after a statement was run through ExecContext,
+	// QueryContext or QueryRowContext.
+	StmtExecuted(ctx context.Context, ev StmtEvent)
+	// Committed is called after the transaction was committed.
+	Committed(ctx context.Context, ev EndEvent)
+	// RolledBack is called after the transaction was rolled back, including
+	// when a commit failed or was vetoed.
+	RolledBack(ctx context.Context, ev EndEvent)
+}
+
+// NopObserver implements Observer with methods that do nothing. Embed it to
+// implement only the events of interest.
+type NopObserver struct{}
+
+func (NopObserver) TxBegan(context.Context, BeginEvent)     {}
+func (NopObserver) StmtPrepared(context.Context, StmtEvent) {}
+func (NopObserver) StmtExecuted(context.Context, StmtEvent) {}
+func (NopObserver) Committed(context.Context, EndEvent)     {}
+func (NopObserver) RolledBack(context.Context, EndEvent)    {}
+
+// BeginEvent describes beginning a transaction.
+type BeginEvent struct {
+	Node     *TxNode
+	Start    time.Time
+	Duration time.Duration
+	Err      error
+}
+
+// StmtEvent describes a statement run through a node.
+type StmtEvent struct {
+	// Node is the node that owns the transaction, which is the parent for
+	// statements run through a Nested child.
+	Node     *TxNode
+	Query    string
+	Start    time.Time
+	Duration time.Duration
+	// RowsAffected is the number of rows affected by an executed statement,
+	// or -1 if it is unknown.
+	RowsAffected int64
+	// Slow reports whether the statement exceeded the threshold set with
+	// WithSlowQueryThreshold.
+	Slow bool
+	// Plan is the execution plan of a slow statement captured with
+	// WithSlowQueryExplain, if any.
+	Plan string
+	Err  error
+}
+
+// EndEvent describes the end of a transaction.
+type EndEvent struct {
+	Node *TxNode
+	// Start is the time the transaction was begun and Duration its age when it ended.
+	Start    time.Time
+	Duration time.Duration
+	// Err is the error that failed the commit or triggered the rollback.
+	Err error
+}
+
+// WithObserver attaches o to the node. It may be given several times.
+func WithObserver(o Observer) Option {
+	return func(c *config) {
+		c.observers = append(c.observers[:len(c.observers):len(c.observers)], o)
+	}
+}
+
+type stmtKind int
+
+const (
+	stmtPrepare stmtKind = iota
+	stmtExec
+	stmtQuery
+)
+
+func (k stmtKind) String() string {
+	switch k {
+	case stmtPrepare:
+		return "prepare"
+	case stmtExec:
+		return "exec"
+	case stmtQuery:
+		return "query"
+	default:
+		return "unknown"
+	}
+}
+
+// runStmt runs op as a statement of the chain: it records the query, retries
+// it under the statement and busy retry policies, logs the statement and
+// reports it to the observers. op returns the number of affected rows, or -1
+// if it is unknown. A failure is returned as a *StmtError.
+func (txn *TxNode) runStmt(
+	ctx context.Context,
+	kind stmtKind,
+	query string,
+	args []any,
+	op func(ctx context.Context) (int64, error),
+) error {
+	txn.record(query)
+
+	// Queries derive their timeout themselves, see queryContext.
+	if d := txn.cfg.stmtTimeout; d > 0 && kind != stmtQuery {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d)
+		defer cancel()
+	}
+
+	txn.explainQueries(ctx, false)
+
+	start := time.Now()
+	rows := int64(-1)
+	err := txn.guardReadOnly(query)
+	if err == nil {
+		err = txn.checkPolicies(ctx, query, args)
+	}
+	if err == nil {
+		err = txn.retry(ctx, txn.cfg.stmtRetry, func() (err error) {
+			if err := txn.cfg.faults.inject(FaultStatement, query); err != nil {
+				return err
+			}
+			rows, err = op(ctx)
+			return err
+		})
+	}
+
+	d := time.Since(start)
+	txn.countStmt(rows)
+	txn.addHistory(kind, query, start, d, rows, err)
+	txn.logStmt(kind, query, args, d, rows, err)
+	slow := txn.isSlow(d)
+	var plan string
+	switch {
+	case !slow:
+	case kind == stmtQuery && err == nil && txn.cfg.explainSlow:
+		txn.deferExplain(query, args, d)
+	default:
+		if kind == stmtExec && err == nil {
+			plan = txn.explain(ctx, query, args, txn.cfg.explainAnalyze)
+		}
+		txn.logSlowStmt(kind, query, d, plan)
+	}
+
+	ev := StmtEvent{
+		Node:         txn.root(),
+		Query:        query,
+		Start:        start,
+		Duration:     d,
+		RowsAffected: rows,
+		Slow:         slow,
+		Plan:         plan,
+		Err:          err,
+	}
+	for _, o := range txn.cfg.observers {
+		if kind == stmtPrepare {
+			o.StmtPrepared(ctx, ev)
+		} else {
+			o.StmtExecuted(ctx, ev)
+		}
+	}
+	if err != nil {
+		return &StmtError{ID: txn.root().id, Op: kind.String(), Err: err}
+	}
+	return nil
+}
+
+// notifyBegin reports beginning the transaction to the observers.
+func (txn *TxNode) notifyBegin(ctx context.Context, start time.Time, err error) {
+	ev := BeginEvent{
+		Node:     txn,
+		Start:    start,
+		Duration: time.Since(start),
+		Err:      err,
+	}
+	for _, o := range txn.cfg.observers {
+		o.TxBegan(ctx, ev)
+	}
+}
+
+// notifyEnd reports the end of the transaction to the observers.
+func (txn *TxNode) notifyEnd(committed bool, err error) {
+	if !txn.markEnded(committed) {
+		return
+	}
+	txn.untrack()
+	txn.logHistory(committed, err)
+	if !committed {
+		txn.logPostMortem(err)
+	}
+	if committed {
+		txn.logDebug("commit transaction")
+	} else if err != nil {
+		txn.logDebug("rollback transaction", txn.debugAttrs(slog.Any("reason", err))...)
+	} else {
+		txn.logDebug("rollback transaction", txn.debugAttrs()...)
+	}
+	if len(txn.cfg.observers) == 0 {
+		return
+	}
+
+	ctx := txn.hookContext()
+	ev := EndEvent{
+		Node:     txn,
+		Start:    txn.began,
+		Duration: time.Since(txn.began),
+		Err:      err,
+	}
+	for _, o := range txn.cfg.observers {
+		if committed {
+			o.Committed(ctx, ev)
+		} else {
+			o.RolledBack(ctx, ev)
+		}
+	}
+}
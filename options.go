@@ -0,0 +1,185 @@
+package txnode
+
+import (
+	"database/sql"
+	"log/slog"
+	"sync/atomic"
+	"time"
+)
+
+// Option configures a TxNode created by New.
+type Option func(*config)
+
+// config holds the settings a TxNode was created with.
+type config struct {
+	txOptions        sql.TxOptions
+	dialect          Dialect
+	logger           Logger
+	timeout          time.Duration
+	retry            RetryPolicy
+	busyRetry        RetryPolicy
+	classifier       Classifier
+	recoverPanics    bool
+	cancelRollback   bool
+	maxDuration      time.Duration
+	stmtCache        bool
+	trackStmt        bool
+	external         bool
+	observers        []Observer
+	queryLog         bool
+	queryLogLevel    slog.Level
+	queryArgs        bool
+	redact           Redactor
+	slowQuery        time.Duration
+	strictCompletion bool
+	noLeakCheck      bool
+	debug            bool
+	outbox           *Outbox
+	inbox            *Inbox
+	invalidator      Invalidator
+	databases        map[string]DB
+	replicas         []DB
+	nextReplica      *atomic.Uint64
+	rebind           Bindvar
+	staleRetry       int
+	statementTimeout time.Duration
+	lockTimeout      time.Duration
+	stmtTimeout      time.Duration
+	beginRetry       RetryPolicy
+	stmtRetry        RetryPolicy
+	breaker          *circuitBreaker
+	limiter          *txLimiter
+	manager          *Manager
+	registry         bool
+	label            string
+	pprofLabels      bool
+	faults           *FaultInjector
+	rollbackOnly     bool
+	dryRun           bool
+	readOnlyGuard    bool
+	policies         []Policy
+	history          bool
+	postMortem       int
+	explainSlow      bool
+	explainAnalyze   bool
+	rowsCheck        bool
+	rowsCheckFail    bool
+	stmtLeakWarn     bool
+	rawQueries       bool
+}
+
+// newConfig returns the default configuration with opts applied.
+func newConfig(opts []Option) config {
+	c := config{
+		trackStmt:  true,
+		classifier: DefaultClassifier(),
+	}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return c
+}
+
+// WithTxOptions sets the options used when the chain begins its transaction,
+// such as the isolation level or read-only mode.
+func WithTxOptions(opts *sql.TxOptions) Option {
+	return func(c *config) {
+		if opts == nil {
+			c.txOptions = sql.TxOptions{}
+			return
+		}
+		c.txOptions = *opts
+	}
+}
+
+// WithIsolation sets the isolation level of the transaction.
+func WithIsolation(level sql.IsolationLevel) Option {
+	return func(c *config) {
+		c.txOptions.Isolation = level
+	}
+}
+
+// WithReadOnly begins the transaction in read-only mode.
+func WithReadOnly() Option {
+	return func(c *config) {
+		c.txOptions.ReadOnly = true
+	}
+}
+
+// WithRollbackOnly makes CommitIfNeeded leave the transaction open instead of
+// committing it, without running the commit hooks, and makes later calls to
+// RollbackTransaction do nothing, so that statements can still inspect what
+// the chain wrote. The transaction is rolled back by Close. It is meant for
+// tests running whole chains against a real database, see
+// txtest.NewRollbackOnly.
+func WithRollbackOnly() Option {
+	return func(c *config) {
+		c.rollbackOnly = true
+	}
+}
+
+// WithLogger sets the logger the node reports its operations to, such as the
+// beginning and end of the transaction, failed commits and aborts. It is also
+// used by RollbackTransactionAndLog when no logger is passed explicitly.
+// Without WithLogger the node does not log.
+func WithLogger(log *slog.Logger) Option {
+	return func(c *config) {
+		if log == nil {
+			c.logger = nil
+			return
+		}
+		c.logger = NewSlogLogger(log)
+	}
+}
+
+// WithCustomLogger is like WithLogger for loggers other than *slog.Logger,
+// see Logger.
+func WithCustomLogger(log Logger) Option {
+	return func(c *config) {
+		c.logger = log
+	}
+}
+
+// WithTimeout bounds the lifetime of the transaction. The transaction is rolled
+// back by database/sql once the timeout elapses, and a later commit fails.
+func WithTimeout(d time.Duration) Option {
+	return func(c *config) {
+		c.timeout = d
+	}
+}
+
+// WithRetryPolicy sets the policy Run uses to retry failed transactions.
+func WithRetryPolicy(p RetryPolicy) Option {
+	return func(c *config) {
+		c.retry = p
+	}
+}
+
+// WithStmtCache enables or disables caching of prepared statements by query
+// text within one transaction. With the cache enabled, PrepareQuery returns the
+// same *sql.Stmt for repeated queries; such statements are shared by the chain
+// and must not be closed by callers, which is why the cache is off by default.
+// Cached statements are closed together with the transaction.
+func WithStmtCache(enabled bool) Option {
+	return func(c *config) {
+		c.stmtCache = enabled
+	}
+}
+
+// WithStmtTracking enables or disables closing of the statements prepared
+// through the node when the transaction is committed or rolled back.
+// Tracking is enabled by default.
+func WithStmtTracking(enabled bool) Option {
+	return func(c *config) {
+		c.trackStmt = enabled
+	}
+}
+
+// log returns the configured logger, falling back to one that discards
+// all records.
+func (c *config) log() Logger {
+	if c.logger != nil {
+		return c.logger
+	}
+	return nopLogger{}
+}
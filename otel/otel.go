@@ -0,0 +1,153 @@
+// Package otel traces txnode transactions with OpenTelemetry.
+//
+// Every transaction becomes a span from begin to commit or rollback, with a
+// child span for each statement prepared or run through the node:
+//
+//	txn := txnode.New(otel.WithTracing(otel.WithDBSystem("postgresql")))
+package otel
+
+import (
+	"context"
+	"sync"
+
+	otelapi "go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/MartellOnell/txnode"
+)
+
+const instrumentationName = "github.com/MartellOnell/txnode/otel"
+
+// Option configures an Observer.
+type Option func(*Observer)
+
+// WithTracerProvider sets the provider the tracer is obtained from.
+// The default is the global provider.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(o *Observer) {
+		o.tracer = tp.Tracer(instrumentationName)
+	}
+}
+
+// WithDBSystem sets the db.system attribute of all spans, for example "postgresql".
+func WithDBSystem(system string) Option {
+	return func(o *Observer) {
+		o.attrs = append(o.attrs, attribute.String("db.system", system))
+	}
+}
+
+// Observer is a txnode.Observer that records transactions as spans.
+type Observer struct {
+	tracer trace.Tracer
+	attrs  []attribute.KeyValue
+	spans  sync.Map // *txnode.TxNode -> trace.Span
+}
+
+var _ txnode.Observer = (*Observer)(nil)
+
+// NewObserver returns an Observer configured with opts.
+func NewObserver(opts ...Option) *Observer {
+	o := &Observer{
+		tracer: otelapi.GetTracerProvider().Tracer(instrumentationName),
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithTracing returns a txnode option that attaches a new Observer.
+func WithTracing(opts ...Option) txnode.Option {
+	return txnode.WithObserver(NewObserver(opts...))
+}
+
+// TxBegan starts the transaction span.
+func (o *Observer) TxBegan(ctx context.Context, ev txnode.BeginEvent) {
+	_, span := o.tracer.Start(ctx, "db.transaction",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithTimestamp(ev.Start),
+		trace.WithAttributes(o.attrs...),
+		trace.WithAttributes(attribute.String("db.transaction.id", ev.Node.ID())),
+	)
+	if ev.Err != nil {
+		endSpan(span, ev.Err, trace.WithTimestamp(ev.Start.Add(ev.Duration)))
+		return
+	}
+	o.spans.Store(ev.Node, span)
+}
+
+// StmtPrepared records a span for preparing a statement.
+func (o *Observer) StmtPrepared(ctx context.Context, ev txnode.StmtEvent) {
+	o.stmtSpan(ctx, "db.prepare", ev)
+}
+
+// StmtExecuted records a span for running a statement.
+func (o *Observer) StmtExecuted(ctx context.Context, ev txnode.StmtEvent) {
+	o.stmtSpan(ctx, "db.query", ev)
+}
+
+// Committed ends the transaction span.
+func (o *Observer) Committed(_ context.Context, ev txnode.EndEvent) {
+	o.endTx(ev, "commit")
+}
+
+// RolledBack ends the transaction span, marking it as failed if the rollback
+// was triggered by an error.
+func (o *Observer) RolledBack(_ context.Context, ev txnode.EndEvent) {
+	o.endTx(ev, "rollback")
+}
+
+func (o *Observer) stmtSpan(ctx context.Context, name string, ev txnode.StmtEvent) {
+	if span, ok := o.txSpan(ev.Node); ok {
+		ctx = trace.ContextWithSpan(ctx, span)
+	}
+
+	attrs := append(o.attrs[:len(o.attrs):len(o.attrs)],
+		attribute.String("db.statement", txnode.SanitizeQuery(ev.Query)),
+	)
+	if ev.RowsAffected >= 0 {
+		attrs = append(attrs, attribute.Int64("db.rows_affected", ev.RowsAffected))
+	}
+	if ev.Slow {
+		attrs = append(attrs,
+			attribute.Bool("db.slow", true),
+			attribute.String("db.fingerprint", txnode.Fingerprint(ev.Query)),
+		)
+	}
+
+	_, span := o.tracer.Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithTimestamp(ev.Start),
+		trace.WithAttributes(attrs...),
+	)
+	endSpan(span, ev.Err, trace.WithTimestamp(ev.Start.Add(ev.Duration)))
+}
+
+func (o *Observer) endTx(ev txnode.EndEvent, outcome string) {
+	v, ok := o.spans.LoadAndDelete(ev.Node)
+	if !ok {
+		return
+	}
+
+	span := v.(trace.Span)
+	span.SetAttributes(attribute.String("db.transaction.outcome", outcome))
+	endSpan(span, ev.Err)
+}
+
+func (o *Observer) txSpan(node *txnode.TxNode) (trace.Span, bool) {
+	v, ok := o.spans.Load(node)
+	if !ok {
+		return nil, false
+	}
+	return v.(trace.Span), true
+}
+
+func endSpan(span trace.Span, err error, opts ...trace.SpanEndOption) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End(opts...)
+}
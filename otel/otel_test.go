@@ -0,0 +1,75 @@
+package otel_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/embedded"
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"github.com/MartellOnell/txnode"
+	"github.com/MartellOnell/txnode/otel"
+	"github.com/MartellOnell/txnode/txtest"
+)
+
+// recorder is a TracerProvider keeping the start attributes of every span.
+type recorder struct {
+	embedded.TracerProvider
+
+	mu    sync.Mutex
+	attrs map[string][]attribute.KeyValue
+}
+
+func (r *recorder) Tracer(string, ...trace.TracerOption) trace.Tracer {
+	return recordingTracer{r: r}
+}
+
+type recordingTracer struct {
+	embedded.Tracer
+	r *recorder
+}
+
+func (t recordingTracer) Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	cfg := trace.NewSpanStartConfig(opts...)
+	t.r.mu.Lock()
+	t.r.attrs[name] = append(t.r.attrs[name], cfg.Attributes()...)
+	t.r.mu.Unlock()
+	return noop.NewTracerProvider().Tracer("").Start(ctx, name, opts...)
+}
+
+func (r *recorder) value(span string, key attribute.Key) (string, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, kv := range r.attrs[span] {
+		if kv.Key == key {
+			return kv.Value.AsString(), true
+		}
+	}
+	return "", false
+}
+
+func TestStatementIsSanitized(t *testing.T) {
+	rec := &recorder{attrs: map[string][]attribute.KeyValue{}}
+	txn := txnode.New(otel.WithTracing(otel.WithTracerProvider(rec)))
+	txn.SetEnd()
+	db := txtest.NewMock().DB()
+
+	const query = `UPDATE users SET password = 'O\'Brien secret' WHERE email = "bob@example.com" -- token abc123secret`
+	if _, err := txn.ExecContext(context.Background(), db, query); err != nil {
+		t.Fatal(err)
+	}
+	if err := txn.CommitIfNeeded(); err != nil {
+		t.Fatal(err)
+	}
+
+	got, ok := rec.value("db.query", "db.statement")
+	if !ok {
+		t.Fatal("no db.statement attribute on the statement span")
+	}
+	if want := "UPDATE users SET password = ? WHERE email = ?"; got != want {
+		t.Errorf("db.statement = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,133 @@
+package txnode
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Event is a message written to the outbox table.
+type Event struct {
+	// ID identifies the event. A random ID is generated if it is empty.
+	ID string
+	// Topic names the destination the event is relayed to.
+	Topic string
+	// Key is an optional partitioning or ordering key.
+	Key string
+	// Payload is the encoded body of the event.
+	Payload []byte
+	// CreatedAt is the time the event was created. It defaults to the time
+	// of PublishOutbox.
+	CreatedAt time.Time
+}
+
+// OutboxSchema is the layout of the outbox table, as names of the table and of
+// its columns. DeliveredAt is the nullable column the relay sets once an event
+// has been delivered.
+type OutboxSchema struct {
+	Table       string
+	ID          string
+	Topic       string
+	Key         string
+	Payload     string
+	CreatedAt   string
+	DeliveredAt string
+}
+
+// DefaultOutboxSchema is the layout used by NewOutbox when none is given:
+//
+//	CREATE TABLE txnode_outbox (
+//		id           varchar(64) PRIMARY KEY,
+//		topic        varchar(255) NOT NULL,
+//		event_key    varchar(255) NOT NULL,
+//		payload      bytea NOT NULL,
+//		created_at   timestamptz NOT NULL,
+//		delivered_at timestamptz
+//	)
+var DefaultOutboxSchema = OutboxSchema{
+	Table:       "txnode_outbox",
+	ID:          "id",
+	Topic:       "topic",
+	Key:         "event_key",
+	Payload:     "payload",
+	CreatedAt:   "created_at",
+	DeliveredAt: "delivered_at",
+}
+
+// Outbox writes events to an outbox table in the transaction of a node, so
+// that they are persisted if and only if the transaction commits. A relay
+// delivers them afterwards.
+type Outbox struct {
+	schema OutboxSchema
+}
+
+// NewOutbox returns an outbox writing to the table described by schema.
+func NewOutbox(schema OutboxSchema) (*Outbox, error) {
+	if !isQualifiedIdentifier(schema.Table) {
+		return nil, fmt.Errorf("txnode: invalid outbox table %q", schema.Table)
+	}
+	for _, col := range []string{
+		schema.ID, schema.Topic, schema.Key, schema.Payload, schema.CreatedAt, schema.DeliveredAt,
+	} {
+		if !isIdentifier(col) {
+			return nil, fmt.Errorf("txnode: invalid outbox column %q", col)
+		}
+	}
+	return &Outbox{schema: schema}, nil
+}
+
+// WithOutbox attaches o to the node, see PublishOutbox.
+func WithOutbox(o *Outbox) Option {
+	return func(c *config) {
+		c.outbox = o
+	}
+}
+
+// PublishOutbox writes events to the outbox attached with WithOutbox, in the
+// node's transaction, beginning it on db if needed. Outside of a transaction,
+// when txn is nil, the events could be persisted without the business data
+// they belong to, so PublishOutbox fails with ErrNotStarted.
+func (txn *TxNode) PublishOutbox(ctx context.Context, db DB, events ...Event) error {
+	if txn == nil {
+		return fmt.Errorf("txnode: publish outbox: %w", ErrNotStarted)
+	}
+	if txn.cfg.outbox == nil {
+		return fmt.Errorf("txnode: publish outbox: no outbox, see WithOutbox")
+	}
+
+	query := txn.cfg.outbox.insertSQL(txn.cfg.dialect)
+	for _, ev := range events {
+		if ev.ID == "" {
+			ev.ID = newID()
+		}
+		if ev.CreatedAt.IsZero() {
+			ev.CreatedAt = time.Now().UTC()
+		}
+		if ev.Payload == nil {
+			ev.Payload = []byte{}
+		}
+		_, err := txn.ExecContext(ctx, db, query, ev.ID, ev.Topic, ev.Key, ev.Payload, ev.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("txnode: publish outbox %s: %w", ev.Topic, err)
+		}
+	}
+	return nil
+}
+
+// insertSQL returns the statement inserting one event in dialect d.
+func (o *Outbox) insertSQL(d Dialect) string {
+	s := o.schema
+	var b strings.Builder
+	b.WriteString("INSERT INTO " + s.Table + " (")
+	b.WriteString(strings.Join([]string{s.ID, s.Topic, s.Key, s.Payload, s.CreatedAt}, ", "))
+	b.WriteString(") VALUES (")
+	for i := 1; i <= 5; i++ {
+		if i > 1 {
+			b.WriteString(", ")
+		}
+		b.WriteString(d.placeholder(i))
+	}
+	b.WriteString(")")
+	return b.String()
+}
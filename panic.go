@@ -0,0 +1,46 @@
+package txnode
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+// PanicError is returned by Run for a panic raised inside the closure when the
+// node is configured with WithRecovery.
+type PanicError struct {
+	// Value is the value passed to panic.
+	Value any
+	// Stack is the stack trace of the panicking goroutine.
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("txnode: panic in transaction: %v", e.Value)
+}
+
+// Unwrap returns the panic value if it is an error.
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// WithRecovery makes Run return a panic raised inside the closure as a
+// *PanicError instead of re-panicking. The transaction is rolled back either way.
+func WithRecovery() Option {
+	return func(c *config) {
+		c.recoverPanics = true
+	}
+}
+
+// Recover rolls back the transaction if the surrounding function is panicking
+// and then re-panics. It must be deferred directly, right after the node is
+// created, to keep a panicking handler from leaking the transaction:
+//
+//	txn := txnode.New()
+//	defer txn.Recover()
+func (txn *TxNode) Recover() {
+	if p := recover(); p != nil {
+		_ = txn.rollback(&PanicError{Value: p, Stack: debug.Stack()})
+		panic(p)
+	}
+}
@@ -0,0 +1,325 @@
+// Package pgxnode provides a transaction node with txnode's chaining semantics
+// for applications that use jackc/pgx natively instead of database/sql.
+package pgxnode
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/MartellOnell/txnode"
+)
+
+// DB is the pgx handle accepted by the node's methods.
+// *pgxpool.Pool and *pgx.Conn implement it.
+type DB interface {
+	BeginTx(ctx context.Context, txOptions pgx.TxOptions) (pgx.Tx, error)
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults
+	CopyFrom(
+		ctx context.Context,
+		tableName pgx.Identifier,
+		columnNames []string,
+		rowSrc pgx.CopyFromSource,
+	) (int64, error)
+}
+
+var (
+	_ DB = (*pgxpool.Pool)(nil)
+	_ DB = (*pgx.Conn)(nil)
+)
+
+var (
+	ErrTransactionArgsMismatch = errors.New("transaction args mismatch")
+)
+
+// TxNode represents a node in a pgx transaction chain.
+// It manages the lifecycle of a pgx.Tx across multiple operations.
+type TxNode struct {
+	isStart bool
+	tx      pgx.Tx
+	isEnd   bool
+	cfg     config
+}
+
+// Option configures a TxNode created by New.
+type Option func(*config)
+
+type config struct {
+	txOptions pgx.TxOptions
+	logger    *slog.Logger
+}
+
+// WithLogger sets the logger used by RollbackTransactionAndLog when no logger
+// is passed explicitly. Without WithLogger nothing is logged in that case.
+func WithLogger(log *slog.Logger) Option {
+	return func(c *config) {
+		c.logger = log
+	}
+}
+
+// WithTxOptions sets the options used when the chain begins its transaction.
+func WithTxOptions(opts pgx.TxOptions) Option {
+	return func(c *config) {
+		c.txOptions = opts
+	}
+}
+
+// New creates a new TxNode ready to start a transaction.
+func New(opts ...Option) *TxNode {
+	txn := &TxNode{
+		isStart: true,
+	}
+	for _, opt := range opts {
+		opt(&txn.cfg)
+	}
+	return txn
+}
+
+// UnsetEnd marks this node as not being the end of the transaction chain.
+func (txn *TxNode) UnsetEnd() {
+	txn.isEnd = false
+}
+
+// SetEnd marks this node as the end of the transaction chain.
+func (txn *TxNode) SetEnd() {
+	txn.isEnd = true
+}
+
+// Tx returns the underlying transaction and whether one has been started.
+func (txn *TxNode) Tx() (pgx.Tx, bool) {
+	if txn == nil || txn.tx == nil {
+		return nil, false
+	}
+	return txn.tx, true
+}
+
+// activeTx returns the chain's transaction, beginning it on db if needed.
+func (txn *TxNode) activeTx(ctx context.Context, db DB) (pgx.Tx, error) {
+	if txn.isStart {
+		tx, err := db.BeginTx(ctx, txn.cfg.txOptions)
+		if err != nil {
+			return nil, err
+		}
+
+		txn.isStart = false
+		txn.tx = tx
+	}
+
+	if txn.tx == nil {
+		return nil, ErrTransactionArgsMismatch
+	}
+
+	return txn.tx, nil
+}
+
+// PrepareQuery prepares a named statement on the transaction's connection.
+// It begins a transaction on first call or reuses the existing transaction.
+// If txn is nil there is no connection to prepare on and it fails with an
+// error wrapping txnode.ErrNotStarted.
+func (txn *TxNode) PrepareQuery(
+	ctx context.Context,
+	db DB,
+	name string,
+	query string,
+) (*pgconn.StatementDescription, error) {
+	if txn == nil {
+		return nil, fmt.Errorf("pgxnode: prepare %s: %w", name, txnode.ErrNotStarted)
+	}
+
+	tx, err := txn.activeTx(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	return tx.Prepare(ctx, name, query)
+}
+
+// Exec executes a query without returning rows. It begins the transaction on
+// first call or reuses the existing one. If txn is nil the query runs on db directly.
+func (txn *TxNode) Exec(
+	ctx context.Context,
+	db DB,
+	query string,
+	args ...any,
+) (pgconn.CommandTag, error) {
+	if txn == nil {
+		return db.Exec(ctx, query, args...)
+	}
+
+	tx, err := txn.activeTx(ctx, db)
+	if err != nil {
+		return pgconn.CommandTag{}, err
+	}
+
+	return tx.Exec(ctx, query, args...)
+}
+
+// Query executes a query that returns rows. It begins the transaction on first
+// call or reuses the existing one. If txn is nil the query runs on db directly.
+func (txn *TxNode) Query(
+	ctx context.Context,
+	db DB,
+	query string,
+	args ...any,
+) (pgx.Rows, error) {
+	if txn == nil {
+		return db.Query(ctx, query, args...)
+	}
+
+	tx, err := txn.activeTx(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	return tx.Query(ctx, query, args...)
+}
+
+// QueryRow executes a query that is expected to return at most one row.
+// An error beginning the transaction is returned by the row's Scan.
+func (txn *TxNode) QueryRow(
+	ctx context.Context,
+	db DB,
+	query string,
+	args ...any,
+) pgx.Row {
+	if txn == nil {
+		return db.QueryRow(ctx, query, args...)
+	}
+
+	tx, err := txn.activeTx(ctx, db)
+	if err != nil {
+		return errRow{err: err}
+	}
+
+	return tx.QueryRow(ctx, query, args...)
+}
+
+// SendBatch sends all queued queries of b to the server at once inside the
+// chain's transaction. An error beginning the transaction is returned by every
+// method of the batch results.
+func (txn *TxNode) SendBatch(ctx context.Context, db DB, b *pgx.Batch) pgx.BatchResults {
+	if txn == nil {
+		return db.SendBatch(ctx, b)
+	}
+
+	tx, err := txn.activeTx(ctx, db)
+	if err != nil {
+		return errBatchResults{err: err}
+	}
+
+	return tx.SendBatch(ctx, b)
+}
+
+// CopyFrom bulk-loads rows into tableName with the COPY protocol inside the
+// chain's transaction. It returns the number of rows copied.
+func (txn *TxNode) CopyFrom(
+	ctx context.Context,
+	db DB,
+	tableName pgx.Identifier,
+	columnNames []string,
+	rowSrc pgx.CopyFromSource,
+) (int64, error) {
+	if txn == nil {
+		return db.CopyFrom(ctx, tableName, columnNames, rowSrc)
+	}
+
+	tx, err := txn.activeTx(ctx, db)
+	if err != nil {
+		return 0, err
+	}
+
+	return tx.CopyFrom(ctx, tableName, columnNames, rowSrc)
+}
+
+// RollbackTransaction rolls back the transaction if one exists.
+func (txn *TxNode) RollbackTransaction(ctx context.Context) error {
+	if txn == nil || txn.tx == nil {
+		return nil
+	}
+
+	return txn.tx.Rollback(ctx)
+}
+
+// CommitIfNeeded commits the transaction only if this node is marked as the end.
+func (txn *TxNode) CommitIfNeeded(ctx context.Context) error {
+	if txn == nil || txn.tx == nil || !txn.isEnd {
+		return nil
+	}
+
+	return txn.tx.Commit(ctx)
+}
+
+// RollbackTransactionAndLog rolls back the transaction and logs both the rollback
+// and the original error. Returns a wrapped error with the operation name,
+// joined with the rollback error if the rollback failed as well.
+// A nil log falls back to the logger configured with WithLogger.
+func (txn *TxNode) RollbackTransactionAndLog(
+	ctx context.Context,
+	log *slog.Logger,
+	op string,
+	err error,
+) error {
+	if log == nil {
+		log = txn.logger()
+	}
+
+	rollbackErr := txn.RollbackTransaction(ctx)
+	if rollbackErr != nil {
+		log.LogAttrs(ctx, slog.LevelError, "rollback transaction",
+			slog.String("op", op), slog.Any("rollback_error", rollbackErr))
+	}
+	log.LogAttrs(ctx, slog.LevelError, "operation failed",
+		slog.String("op", op), slog.Any("error", err))
+
+	err = fmt.Errorf("%s: %w", op, err)
+	if rollbackErr != nil {
+		return errors.Join(err, fmt.Errorf("%s: rollback transaction: %w", op, rollbackErr))
+	}
+	return err
+}
+
+// logger returns the configured logger, or one that discards all records.
+func (txn *TxNode) logger() *slog.Logger {
+	if txn == nil || txn.cfg.logger == nil {
+		return slog.New(slog.DiscardHandler)
+	}
+	return txn.cfg.logger
+}
+
+// errRow is a pgx.Row that reports err from Scan.
+type errRow struct {
+	err error
+}
+
+func (r errRow) Scan(...any) error {
+	return r.err
+}
+
+// errBatchResults is a pgx.BatchResults that reports err from every method.
+type errBatchResults struct {
+	err error
+}
+
+func (r errBatchResults) Exec() (pgconn.CommandTag, error) {
+	return pgconn.CommandTag{}, r.err
+}
+
+func (r errBatchResults) Query() (pgx.Rows, error) {
+	return nil, r.err
+}
+
+func (r errBatchResults) QueryRow() pgx.Row {
+	return errRow{err: r.err}
+}
+
+func (r errBatchResults) Close() error {
+	return r.err
+}
@@ -0,0 +1,61 @@
+package txnode
+
+import (
+	"context"
+	"fmt"
+)
+
+// Policy decides whether a statement may run through a node, such as to forbid
+// DDL in request-path transactions or to require a tenant_id condition. Check
+// is called before every statement is prepared or executed, with nil args for
+// prepared statements, and rejects the statement by returning an error.
+type Policy interface {
+	Check(ctx context.Context, query string, args []any) error
+}
+
+// PolicyFunc adapts a function to the Policy interface.
+type PolicyFunc func(ctx context.Context, query string, args []any) error
+
+// Check calls f.
+func (f PolicyFunc) Check(ctx context.Context, query string, args []any) error {
+	return f(ctx, query, args)
+}
+
+// PolicyError reports a statement rejected by a Policy. The transaction has
+// been aborted.
+type PolicyError struct {
+	Query string
+	Err   error
+}
+
+func (e *PolicyError) Error() string {
+	return fmt.Sprintf("txnode: policy rejected %s: %v", e.Query, e.Err)
+}
+
+func (e *PolicyError) Unwrap() error {
+	return e.Err
+}
+
+// WithPolicy checks every statement run through the node against p. A
+// statement p rejects is not run and aborts the whole transaction, so that
+// the chain cannot commit work done around the violation; the statement fails
+// with a *PolicyError and so does the commit. It may be given several times,
+// the policies being checked in order.
+func WithPolicy(p Policy) Option {
+	return func(c *config) {
+		c.policies = append(c.policies[:len(c.policies):len(c.policies)], p)
+	}
+}
+
+// checkPolicies runs the policies of the node on a statement, aborting the
+// transaction if one rejects it.
+func (txn *TxNode) checkPolicies(ctx context.Context, query string, args []any) error {
+	for _, p := range txn.cfg.policies {
+		if err := p.Check(ctx, query, args); err != nil {
+			perr := &PolicyError{Query: txn.sanitize(query), Err: err}
+			txn.root().abort(perr)
+			return perr
+		}
+	}
+	return nil
+}
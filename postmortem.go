@@ -0,0 +1,39 @@
+package txnode
+
+import (
+	"log/slog"
+	"time"
+)
+
+// WithPostMortem logs a dump of the transaction at error level whenever it is
+// rolled back, including after a failed commit or an abort: the error that
+// triggered the rollback, the last n statements with their durations, where
+// the transaction was begun and its age. It tells how the chain got to the
+// failure, where RollbackTransactionAndLog only reports the final one.
+// Records go to the logger set with WithLogger.
+func WithPostMortem(n int) Option {
+	return func(c *config) {
+		c.postMortem = n
+	}
+}
+
+// logPostMortem logs the dump of a transaction that was rolled back.
+func (txn *TxNode) logPostMortem(reason error) {
+	if txn.cfg.postMortem <= 0 || !enabled(txn.logContext(), txn.logger(), slog.LevelError) {
+		return
+	}
+
+	stmts := txn.statements()
+	if len(stmts) > txn.cfg.postMortem {
+		stmts = stmts[len(stmts)-txn.cfg.postMortem:]
+	}
+	attrs := []slog.Attr{
+		slog.String("begin_caller", txn.beginCaller),
+		slog.Duration("age", time.Since(txn.began)),
+		historyGroup(stmts),
+	}
+	if reason != nil {
+		attrs = append(attrs, slog.Any("reason", reason))
+	}
+	txn.log(slog.LevelError, "transaction post-mortem", attrs...)
+}
@@ -0,0 +1,39 @@
+package txnode
+
+import (
+	"context"
+	"runtime/pprof"
+)
+
+// WithProfilerLabels makes Run and Chain.Run execute their closures under
+// pprof labels, so that CPU and goroutine profiles attribute the work of a
+// chain to it: tx_label carries the label set with WithLabel, and op the name
+// of the running Chain operation. Labels are inherited by the goroutines the
+// closures start.
+func WithProfilerLabels() Option {
+	return func(c *config) {
+		c.pprofLabels = true
+	}
+}
+
+// profile calls fn with ctx, under the pprof labels of the node and, if op is
+// not empty, of the operation op when WithProfilerLabels is set.
+func (txn *TxNode) profile(ctx context.Context, op string, fn func(ctx context.Context) error) error {
+	if !txn.cfg.pprofLabels {
+		return fn(ctx)
+	}
+
+	labels := []string{"tx_label", txn.cfg.label}
+	if txn.cfg.label == "" {
+		labels[1] = "unlabeled"
+	}
+	if op != "" {
+		labels = append(labels, "op", op)
+	}
+
+	var err error
+	pprof.Do(ctx, pprof.Labels(labels...), func(ctx context.Context) {
+		err = fn(ctx)
+	})
+	return err
+}
@@ -0,0 +1,168 @@
+package txnode
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrUnsupported is returned for features the node's dialect lacks.
+	ErrUnsupported = errors.New("not supported by the dialect")
+	// ErrInvalidGID is returned for global transaction IDs that cannot be
+	// used with prepared transactions.
+	ErrInvalidGID = errors.New("invalid global transaction id")
+	// ErrPrepared is returned when a node whose transaction has been prepared
+	// is committed or rolled back; prepared transactions are finished on the
+	// database by their global transaction ID.
+	ErrPrepared = errors.New("transaction prepared")
+)
+
+// maxGIDLength is the longest global transaction ID Postgres accepts.
+const maxGIDLength = 200
+
+// PrepareTransaction runs the before-commit hooks and prepares the node's
+// transaction for a two-phase commit under the global transaction ID gid, with
+// Postgres' PREPARE TRANSACTION. The node's connection is released and the
+// prepared transaction lives on in the database, surviving crashes, until it is
+// finished with CommitPrepared or RollbackPrepared. OnCommit hooks are
+// discarded, since the node does not learn the outcome. The node's state
+// becomes StatePrepared, and observers are told that the transaction
+// committed, its work being done.
+//
+// Prepared transactions require max_prepared_transactions to be set on the
+// server. Only DialectPostgres supports them.
+func (txn *TxNode) PrepareTransaction(ctx context.Context, gid string) error {
+	if txn == nil || txn.tx == nil {
+		return ErrNotStarted
+	}
+	if txn.savepoint != "" {
+		return fmt.Errorf("txnode: prepare transaction: %w: node is nested", ErrTransactionArgsMismatch)
+	}
+	if txn.cfg.dialect != DialectPostgres {
+		return fmt.Errorf("txnode: prepare transaction: %w: %s", ErrUnsupported, txn.cfg.dialect)
+	}
+	if !isGID(gid) {
+		return fmt.Errorf("%w: %q", ErrInvalidGID, gid)
+	}
+	if err := txn.Err(); err != nil {
+		txn.release()
+		return err
+	}
+	if done, err := txn.finished(true); done {
+		return err
+	}
+
+	if err := txn.runBeforeCommitHooks(); err != nil {
+		txn.release()
+		return err
+	}
+
+	// An abort racing the statement leaves the end of the transaction to it.
+	txn.mu.Lock()
+	txn.committing = true
+	txn.mu.Unlock()
+	query := "PREPARE TRANSACTION '" + gid + "'"
+	err := txn.runStmt(ctx, stmtExec, query, nil, func(ctx context.Context) (int64, error) {
+		_, err := txn.tx.ExecContext(ctx, query)
+		return -1, err
+	})
+	if err != nil {
+		return txn.rollbackJoin(err)
+	}
+
+	// The session is no longer in a transaction once it is prepared; ending
+	// the *sql.Tx returns its connection to the pool.
+	_ = txn.tx.Commit()
+
+	txn.setState(StatePrepared)
+	txn.notifyEnd(true, nil)
+	txn.release()
+	return nil
+}
+
+// rollbackJoin rolls the transaction back because of err and returns err joined
+// with the rollback error, if any.
+func (txn *TxNode) rollbackJoin(err error) error {
+	if rollbackErr := txn.rollback(err); rollbackErr != nil {
+		return errors.Join(err, rollbackErr)
+	}
+	return err
+}
+
+// CommitPrepared commits the prepared transaction gid on db, which may be any
+// connection to the database the transaction was prepared on.
+func CommitPrepared(ctx context.Context, db Execer, gid string) error {
+	return execPrepared(ctx, db, "COMMIT PREPARED", gid)
+}
+
+// RollbackPrepared rolls back the prepared transaction gid on db, which may be
+// any connection to the database the transaction was prepared on.
+func RollbackPrepared(ctx context.Context, db Execer, gid string) error {
+	return execPrepared(ctx, db, "ROLLBACK PREPARED", gid)
+}
+
+func execPrepared(ctx context.Context, db Execer, cmd, gid string) error {
+	if !isGID(gid) {
+		return fmt.Errorf("%w: %q", ErrInvalidGID, gid)
+	}
+	if _, err := db.ExecContext(ctx, cmd+" '"+gid+"'"); err != nil {
+		return fmt.Errorf("txnode: %s %s: %w", cmd, gid, err)
+	}
+	return nil
+}
+
+// isGID reports whether gid can be embedded in a prepared transaction
+// statement as is.
+func isGID(gid string) bool {
+	if gid == "" || len(gid) > maxGIDLength {
+		return false
+	}
+	for i := 0; i < len(gid); i++ {
+		c := gid[i]
+		if !isIdentByte(c) && c != '-' && c != '.' && c != ':' {
+			return false
+		}
+	}
+	return true
+}
+
+// PreparedTx describes a prepared transaction found by ListPrepared.
+type PreparedTx struct {
+	GID      string
+	Prepared time.Time
+	Owner    string
+	Database string
+}
+
+// ListPrepared returns the prepared transactions of the current database
+// whose global transaction ID starts with prefix, oldest first, as listed in
+// pg_prepared_xacts. After a crash it finds the transactions that must be
+// resolved with CommitPrepared or RollbackPrepared; the branches prepared by a
+// Coordinator start with GIDPrefix.
+func ListPrepared(ctx context.Context, db Querier, prefix string) ([]PreparedTx, error) {
+	rows, err := db.QueryContext(ctx,
+		"SELECT gid, prepared, owner, database FROM pg_prepared_xacts "+
+			"WHERE database = current_database() ORDER BY prepared")
+	if err != nil {
+		return nil, fmt.Errorf("txnode: list prepared transactions: %w", err)
+	}
+	defer rows.Close()
+
+	var out []PreparedTx
+	for rows.Next() {
+		var p PreparedTx
+		if err := rows.Scan(&p.GID, &p.Prepared, &p.Owner, &p.Database); err != nil {
+			return nil, fmt.Errorf("txnode: list prepared transactions: %w", err)
+		}
+		if strings.HasPrefix(p.GID, prefix) {
+			out = append(out, p)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("txnode: list prepared transactions: %w", err)
+	}
+	return out, nil
+}
@@ -0,0 +1,54 @@
+package txnode_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+
+	"github.com/MartellOnell/txnode"
+)
+
+func TestPrepareTransactionEndsNode(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO t (v) VALUES (1)").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("PREPARE TRANSACTION 'order-42'").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	ends := &endCounter{}
+	m := txnode.NewManager(db, txnode.WithObserver(ends), txnode.WithRegistry(),
+		txnode.WithLabel("prepare-test"), txnode.WithCancelRollback())
+	txn := m.New()
+	ctx := context.Background()
+	if _, err := txn.ExecContext(ctx, db, "INSERT INTO t (v) VALUES (1)"); err != nil {
+		t.Fatal(err)
+	}
+	if err := txn.PrepareTransaction(ctx, "order-42"); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := txn.State(); got != txnode.StatePrepared {
+		t.Errorf("State() = %v, want %v", got, txnode.StatePrepared)
+	}
+	if got := ends.committed.Load(); got != 1 || ends.ends() != 1 {
+		t.Errorf("observers told of %d commits and %d ends, want 1 commit", got, ends.ends())
+	}
+	for _, info := range txnode.ActiveTransactions() {
+		if info.Label == "prepare-test" {
+			t.Errorf("prepared transaction %s still registered", info.ID)
+		}
+	}
+	shutdown, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	if err := m.Shutdown(shutdown); err != nil {
+		t.Errorf("Shutdown() = %v, want the manager drained", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
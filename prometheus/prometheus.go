@@ -0,0 +1,195 @@
+// Package prometheus exports metrics about txnode transactions to Prometheus.
+//
+// A Collector observes the nodes it is attached to and must be registered
+// with a Prometheus registry:
+//
+//	c := prometheus.NewCollector()
+//	registry.MustRegister(c)
+//	txn := txnode.New(prometheus.WithMetrics(c))
+package prometheus
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+
+	"github.com/MartellOnell/txnode"
+)
+
+// Option configures a Collector.
+type Option func(*options)
+
+type options struct {
+	namespace string
+	buckets   []float64
+}
+
+// WithNamespace sets the namespace of all metric names. The default is "txnode".
+func WithNamespace(ns string) Option {
+	return func(o *options) {
+		o.namespace = ns
+	}
+}
+
+// WithDurationBuckets sets the buckets of the transaction duration histogram,
+// in seconds. The default is prometheus.DefBuckets.
+func WithDurationBuckets(buckets []float64) Option {
+	return func(o *options) {
+		o.buckets = buckets
+	}
+}
+
+// Collector is a txnode.Observer that maintains transaction metrics and
+// implements prometheus.Collector.
+type Collector struct {
+	begun      prom.Counter
+	beginFails prom.Counter
+	committed  prom.Counter
+	rolledBack *prom.CounterVec
+	duration   *prom.HistogramVec
+	statements prom.Histogram
+	slow       prom.Counter
+
+	counts sync.Map // *txnode.TxNode -> *atomic.Int64
+}
+
+var (
+	_ txnode.Observer = (*Collector)(nil)
+	_ prom.Collector  = (*Collector)(nil)
+)
+
+// NewCollector returns a Collector configured with opts.
+func NewCollector(opts ...Option) *Collector {
+	o := options{
+		namespace: "txnode",
+		buckets:   prom.DefBuckets,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return &Collector{
+		begun: prom.NewCounter(prom.CounterOpts{
+			Namespace: o.namespace,
+			Name:      "transactions_begun_total",
+			Help:      "Number of transactions begun.",
+		}),
+		beginFails: prom.NewCounter(prom.CounterOpts{
+			Namespace: o.namespace,
+			Name:      "transaction_begin_failures_total",
+			Help:      "Number of failed attempts to begin a transaction.",
+		}),
+		committed: prom.NewCounter(prom.CounterOpts{
+			Namespace: o.namespace,
+			Name:      "transactions_committed_total",
+			Help:      "Number of transactions committed.",
+		}),
+		rolledBack: prom.NewCounterVec(prom.CounterOpts{
+			Namespace: o.namespace,
+			Name:      "transactions_rolled_back_total",
+			Help:      "Number of transactions rolled back, by class of the triggering error.",
+		}, []string{"reason"}),
+		duration: prom.NewHistogramVec(prom.HistogramOpts{
+			Namespace: o.namespace,
+			Name:      "transaction_duration_seconds",
+			Help:      "Time from begin to commit or rollback of a transaction.",
+			Buckets:   o.buckets,
+		}, []string{"outcome"}),
+		statements: prom.NewHistogram(prom.HistogramOpts{
+			Namespace: o.namespace,
+			Name:      "statements_per_transaction",
+			Help:      "Number of statements prepared or run per transaction.",
+			Buckets:   prom.ExponentialBuckets(1, 2, 10),
+		}),
+		slow: prom.NewCounter(prom.CounterOpts{
+			Namespace: o.namespace,
+			Name:      "slow_statements_total",
+			Help:      "Number of statements that exceeded the slow query threshold.",
+		}),
+	}
+}
+
+// WithMetrics returns a txnode option that attaches c to the node.
+func WithMetrics(c *Collector) txnode.Option {
+	return txnode.WithObserver(c)
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prom.Desc) {
+	c.begun.Describe(ch)
+	c.beginFails.Describe(ch)
+	c.committed.Describe(ch)
+	c.rolledBack.Describe(ch)
+	c.duration.Describe(ch)
+	c.statements.Describe(ch)
+	c.slow.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prom.Metric) {
+	c.begun.Collect(ch)
+	c.beginFails.Collect(ch)
+	c.committed.Collect(ch)
+	c.rolledBack.Collect(ch)
+	c.duration.Collect(ch)
+	c.statements.Collect(ch)
+	c.slow.Collect(ch)
+}
+
+// TxBegan implements txnode.Observer.
+func (c *Collector) TxBegan(_ context.Context, ev txnode.BeginEvent) {
+	if ev.Err != nil {
+		c.beginFails.Inc()
+		return
+	}
+	c.begun.Inc()
+	c.counts.Store(ev.Node, new(atomic.Int64))
+}
+
+// StmtPrepared implements txnode.Observer.
+func (c *Collector) StmtPrepared(_ context.Context, ev txnode.StmtEvent) {
+	c.countStmt(ev)
+}
+
+// StmtExecuted implements txnode.Observer.
+func (c *Collector) StmtExecuted(_ context.Context, ev txnode.StmtEvent) {
+	c.countStmt(ev)
+}
+
+// Committed implements txnode.Observer.
+func (c *Collector) Committed(_ context.Context, ev txnode.EndEvent) {
+	c.committed.Inc()
+	c.end(ev, "commit")
+}
+
+// RolledBack implements txnode.Observer.
+func (c *Collector) RolledBack(_ context.Context, ev txnode.EndEvent) {
+	c.rolledBack.WithLabelValues(reason(ev.Err)).Inc()
+	c.end(ev, "rollback")
+}
+
+func (c *Collector) countStmt(ev txnode.StmtEvent) {
+	if ev.Slow {
+		c.slow.Inc()
+	}
+	if v, ok := c.counts.Load(ev.Node); ok {
+		v.(*atomic.Int64).Add(1)
+	}
+}
+
+func (c *Collector) end(ev txnode.EndEvent, outcome string) {
+	c.duration.WithLabelValues(outcome).Observe(ev.Duration.Seconds())
+	if v, ok := c.counts.LoadAndDelete(ev.Node); ok {
+		c.statements.Observe(float64(v.(*atomic.Int64).Load()))
+	}
+}
+
+// reason returns the rollback reason label for err.
+func reason(err error) string {
+	if err == nil {
+		return "explicit"
+	}
+	return txnode.Classify(err).String()
+}
@@ -0,0 +1,104 @@
+package txnode
+
+import (
+	"context"
+	"fmt"
+)
+
+// Propagation selects how a child node returned by Propagate relates to the
+// transaction of its parent.
+type Propagation int
+
+const (
+	// Required joins the parent's transaction. Without a parent a new
+	// transaction is started and committed by the child.
+	Required Propagation = iota
+	// RequiresNew always starts an independent transaction that is committed
+	// or rolled back by the child regardless of the parent's outcome.
+	RequiresNew
+	// Nested runs inside the parent's transaction behind a savepoint.
+	// Committing the child releases the savepoint and rolling it back undoes
+	// only the child's work. Without a parent it behaves like RequiresNew.
+	Nested
+)
+
+// Propagate returns a child node whose transaction follows mode.
+// Children that own their work are already marked as the end of their chain,
+// so CommitIfNeeded on them commits (or releases the savepoint). A Required
+// child of a non-nil parent is the parent itself.
+func (txn *TxNode) Propagate(
+	ctx context.Context,
+	db DB,
+	mode Propagation,
+) (*TxNode, error) {
+	switch mode {
+	case Required:
+		if txn != nil {
+			return txn, nil
+		}
+		return txn.child(), nil
+	case RequiresNew:
+		child := txn.child()
+		if err := child.begin(ctx, db); err != nil {
+			return nil, err
+		}
+		return child, nil
+	case Nested:
+		if txn == nil {
+			return txn.Propagate(ctx, db, RequiresNew)
+		}
+		return txn.nested(ctx, db)
+	default:
+		return nil, fmt.Errorf("txnode: unknown propagation mode %d", mode)
+	}
+}
+
+// child returns a fresh end node that inherits the configuration of txn.
+func (txn *TxNode) child() *TxNode {
+	child := &TxNode{
+		isStart: true,
+		isEnd:   true,
+	}
+	if txn != nil {
+		child.cfg = txn.cfg
+	} else {
+		child.cfg = newConfig(nil)
+	}
+	return child
+}
+
+func (txn *TxNode) nested(ctx context.Context, db DB) (*TxNode, error) {
+	tx, err := txn.activeTx(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	name := txn.nextSavepoint()
+	if err := txn.Savepoint(ctx, name); err != nil {
+		return nil, err
+	}
+
+	return &TxNode{
+		tx:        tx,
+		isEnd:     true,
+		cfg:       txn.cfg,
+		parent:    txn,
+		savepoint: name,
+		state:     StateActive,
+	}, nil
+}
+
+// nextSavepoint returns a savepoint name not used before in the transaction.
+func (txn *TxNode) nextSavepoint() string {
+	root := txn.root()
+	root.savepoints++
+	return fmt.Sprintf("txnode_sp_%d", root.savepoints)
+}
+
+// root returns the node that owns the underlying transaction.
+func (txn *TxNode) root() *TxNode {
+	for txn.parent != nil {
+		txn = txn.parent
+	}
+	return txn
+}
@@ -0,0 +1,161 @@
+package txnode
+
+import (
+	"context"
+	"database/sql"
+	"sync/atomic"
+)
+
+// Row is the result of QueryRowContext. It defers any error from beginning the
+// transaction to Scan, mirroring the behavior of *sql.Row.
+type Row struct {
+	row *sql.Row
+	err error
+	// scanned is set once the row was scanned, which frees the connection.
+	scanned atomic.Bool
+	// cancel releases the context derived for WithPerStatementTimeout.
+	cancel context.CancelFunc
+}
+
+// Scan copies the columns of the matched row into dest.
+func (r *Row) Scan(dest ...any) error {
+	if r.err != nil {
+		return r.err
+	}
+	defer r.scanned.Store(true)
+	if r.cancel != nil {
+		defer r.cancel()
+	}
+	return r.row.Scan(dest...)
+}
+
+// Err returns the error, if any, that was encountered while running the query.
+func (r *Row) Err() error {
+	if r.err != nil {
+		return r.err
+	}
+	return r.row.Err()
+}
+
+// ExecContext executes a query without returning rows. It begins the transaction
+// on first call or reuses the existing one. If txn is nil the query runs on db directly.
+func (txn *TxNode) ExecContext(
+	ctx context.Context,
+	db DB,
+	query string,
+	args ...any,
+) (sql.Result, error) {
+	if txn == nil {
+		return db.ExecContext(ctx, query, args...)
+	}
+
+	tx, err := txn.activeTx(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	query = txn.rebind(query)
+
+	var res sql.Result
+	err = txn.runStmt(ctx, stmtExec, query, args, func(ctx context.Context) (int64, error) {
+		res, err = tx.ExecContext(ctx, query, args...)
+		if err != nil {
+			return -1, err
+		}
+		return rowsAffected(res), nil
+	})
+	return res, err
+}
+
+// QueryContext executes a query that returns rows. It begins the transaction
+// on first call or reuses the existing one. If txn is nil the query runs on db directly.
+func (txn *TxNode) QueryContext(
+	ctx context.Context,
+	db DB,
+	query string,
+	args ...any,
+) (*sql.Rows, error) {
+	if txn == nil {
+		return db.QueryContext(ctx, query, args...)
+	}
+
+	tx, err := txn.activeTx(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	query = txn.rebind(query)
+
+	ctx, cancel := txn.queryContext(ctx)
+	var rows *sql.Rows
+	err = txn.runStmt(ctx, stmtQuery, query, args, func(ctx context.Context) (int64, error) {
+		rows, err = tx.QueryContext(ctx, query, args...)
+		return -1, err
+	})
+	if err != nil {
+		if cancel != nil {
+			cancel()
+		}
+		return nil, err
+	}
+
+	open := func() bool {
+		// Columns fails once the rows are closed.
+		_, err := rows.Columns()
+		return err == nil
+	}
+	txn.trackRows(rows, query)
+	txn.watchSlowQuery(open)
+	txn.holdStmtCancel(cancel, open)
+	return rows, nil
+}
+
+// QueryRowContext executes a query that is expected to return at most one row.
+// It begins the transaction on first call or reuses the existing one.
+// If txn is nil the query runs on db directly.
+func (txn *TxNode) QueryRowContext(
+	ctx context.Context,
+	db DB,
+	query string,
+	args ...any,
+) *Row {
+	if txn == nil {
+		return &Row{row: db.QueryRowContext(ctx, query, args...)}
+	}
+
+	tx, err := txn.activeTx(ctx, db)
+	if err != nil {
+		return &Row{err: err}
+	}
+
+	query = txn.rebind(query)
+
+	ctx, cancel := txn.queryContext(ctx)
+	var row *sql.Row
+	err = txn.runStmt(ctx, stmtQuery, query, args, func(ctx context.Context) (int64, error) {
+		row = tx.QueryRowContext(ctx, query, args...)
+		return -1, row.Err()
+	})
+	if err != nil {
+		if cancel != nil {
+			cancel()
+		}
+		return &Row{err: err}
+	}
+
+	r := &Row{row: row, cancel: cancel}
+	open := func() bool { return !r.scanned.Load() }
+	txn.watchSlowQuery(open)
+	txn.holdStmtCancel(cancel, open)
+	return r
+}
+
+// rowsAffected returns the number of rows affected by res, or -1 if the
+// driver does not report it.
+func rowsAffected(res sql.Result) int64 {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return -1
+	}
+	return n
+}
@@ -0,0 +1,96 @@
+package txnode
+
+import (
+	"log/slog"
+	"time"
+)
+
+// Redactor returns the value to log in place of the bind argument arg at
+// position i of a statement, see WithQueryArgs.
+type Redactor func(i int, arg any) any
+
+// RedactedArg is the value RedactAll logs in place of every argument.
+const RedactedArg = "[REDACTED]"
+
+// RedactAll is a Redactor that hides every argument.
+func RedactAll(int, any) any {
+	return RedactedArg
+}
+
+// WithQueryLogging logs every statement prepared or executed through the node
+// at level, together with its duration, the number of affected rows and the
+// error if it failed. Statements are logged sanitized, with their fingerprint,
+// see SanitizeQueryDialect and WithRawQueryText. The bind arguments are not logged,
+// only their number; see WithQueryArgs. Records go to the logger set with
+// WithLogger.
+func WithQueryLogging(level slog.Level) Option {
+	return func(c *config) {
+		c.queryLog = true
+		c.queryLogLevel = level
+	}
+}
+
+// WithQueryArgs makes WithQueryLogging include the bind arguments of each
+// statement. Every argument is passed through redact first, so that sensitive
+// values can be masked; a nil redact logs the values as they are.
+func WithQueryArgs(redact Redactor) Option {
+	return func(c *config) {
+		c.queryArgs = true
+		c.redact = redact
+	}
+}
+
+// WithRawQueryText makes WithQueryLogging log the text of each statement as
+// it was given instead of sanitized, literals included. Use it only where the
+// logs may hold the values embedded in the statements.
+func WithRawQueryText() Option {
+	return func(c *config) {
+		c.rawQueries = true
+	}
+}
+
+// logStmt logs a statement for WithQueryLogging.
+func (txn *TxNode) logStmt(kind stmtKind, query string, args []any, d time.Duration, rows int64, err error) {
+	if !txn.cfg.queryLog && !txn.cfg.dryRun {
+		return
+	}
+
+	text := query
+	if !txn.cfg.rawQueries {
+		text = txn.sanitize(query)
+	}
+	attrs := []slog.Attr{
+		slog.String("kind", kind.String()),
+		slog.String("query", text),
+		slog.String("fingerprint", Fingerprint(query)),
+		slog.Duration("stmt_duration", d),
+	}
+	if txn.cfg.queryArgs {
+		attrs = append(attrs, slog.Any("args", txn.redactArgs(args)))
+	} else {
+		attrs = append(attrs, slog.Int("arg_count", len(args)))
+	}
+	if rows >= 0 {
+		attrs = append(attrs, slog.Int64("rows_affected", rows))
+	}
+	if err != nil {
+		attrs = append(attrs, slog.Any("error", err))
+	}
+	if txn.cfg.dryRun {
+		attrs = append(attrs, slog.Bool("dry_run", true))
+	}
+	txn.log(txn.cfg.queryLogLevel, "statement", attrs...)
+}
+
+// redactArgs returns args passed through the configured Redactor.
+func (txn *TxNode) redactArgs(args []any) []any {
+	if txn.cfg.redact == nil {
+		return args
+	}
+
+	out := make([]any, len(args))
+	for i, arg := range args {
+		out[i] = txn.cfg.redact(i, arg)
+	}
+	return out
+}
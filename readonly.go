@@ -0,0 +1,90 @@
+package txnode
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrWriteInReadOnlyTx is returned for a statement that may write, run through
+// a node created with WithReadOnlyGuard.
+var ErrWriteInReadOnlyTx = errors.New("write statement in read-only transaction")
+
+// WithReadOnlyGuard rejects, with an error wrapping ErrWriteInReadOnlyTx, every
+// statement that may write before it reaches the database. Statements are
+// inspected by keyword: reads as defined by IsReadQuery are allowed, as are
+// session statements such as SET, SHOW, EXPLAIN of a read, and the cursor
+// statements of DeclareCursor. The transaction is also begun in read-only
+// mode, as with WithReadOnly, except on SQL Server whose driver does not
+// support it.
+func WithReadOnlyGuard() Option {
+	return func(c *config) {
+		c.readOnlyGuard = true
+	}
+}
+
+// txOptions returns the options to begin the transaction with.
+func (txn *TxNode) txOptions() *sql.TxOptions {
+	opts := txn.cfg.txOptions
+	if txn.cfg.readOnlyGuard && txn.cfg.dialect != DialectSQLServer {
+		opts.ReadOnly = true
+	}
+	return &opts
+}
+
+// guardReadOnly returns an error if the node has a read-only guard and query
+// may write.
+func (txn *TxNode) guardReadOnly(query string) error {
+	if !txn.cfg.readOnlyGuard || !isWriteQuery(query) {
+		return nil
+	}
+	return fmt.Errorf("txnode: %w: %s", ErrWriteInReadOnlyTx, txn.sanitize(query))
+}
+
+// isWriteQuery reports whether query may write data or change the schema.
+func isWriteQuery(query string) bool {
+	q := sanitizeQuery(query, quoting{})
+	if isSelectQuery(q) {
+		return false
+	}
+
+	words := strings.Fields(strings.ToUpper(q))
+	if len(words) == 0 {
+		return false
+	}
+	switch strings.Trim(words[0], "(;") {
+	case "SET", "RESET", "SHOW", "FETCH", "MOVE", "CLOSE", "SAVEPOINT", "RELEASE":
+		return false
+	case "DECLARE":
+		// DECLARE name CURSOR FOR query.
+		for i, w := range words {
+			if w == "FOR" {
+				return isWriteQuery(strings.Join(words[i+1:], " "))
+			}
+		}
+	case "EXPLAIN":
+		// EXPLAIN only runs the statement with ANALYZE, given either as a
+		// keyword or in a parenthesized option list.
+		rest := words[1:]
+		analyze, inList := false, false
+		for len(rest) > 0 {
+			w := rest[0]
+			switch {
+			case strings.HasPrefix(w, "("):
+				inList = true
+			case !inList && w != "ANALYZE" && w != "VERBOSE":
+				return analyze && isWriteQuery(strings.Join(rest, " "))
+			}
+			if strings.Trim(w, "(),") == "ANALYZE" {
+				analyze = true
+			}
+			if strings.HasSuffix(w, ")") {
+				inList = false
+			}
+			rest = rest[1:]
+		}
+		return false
+	}
+	return true
+}
@@ -0,0 +1,139 @@
+package txnode
+
+import (
+	"reflect"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WithRegistry registers the node's transaction, while it is open, in the
+// process-wide registry listed by ActiveTransactions, so that operators can
+// see what holds the connections during an incident.
+func WithRegistry() Option {
+	return func(c *config) {
+		c.registry = true
+	}
+}
+
+// WithLabel labels the node's transaction with label, a short description of
+// the work it does, such as "checkout", shown by ActiveTransactions.
+func WithLabel(label string) Option {
+	return func(c *config) {
+		c.label = label
+	}
+}
+
+// TxInfo describes an open transaction, see ActiveTransactions.
+type TxInfo struct {
+	ID    string
+	Label string
+	State State
+	Began time.Time
+	Age   time.Duration
+	// Statements is the number of statements run so far.
+	Statements int
+	// LastQuery is the most recent statement of the transaction.
+	LastQuery string
+	// Caller is the function, file and line outside this package that began
+	// the transaction.
+	Caller string
+}
+
+// ActiveTransactions returns the open transactions of the nodes configured
+// with WithRegistry, oldest first.
+func ActiveTransactions() []TxInfo {
+	registry.mu.Lock()
+	nodes := make(map[*TxNode]string, len(registry.nodes))
+	for txn, caller := range registry.nodes {
+		nodes[txn] = caller
+	}
+	registry.mu.Unlock()
+
+	infos := make([]TxInfo, 0, len(nodes))
+	for txn, caller := range nodes {
+		info := TxInfo{
+			ID:         txn.id,
+			Label:      txn.cfg.label,
+			State:      txn.State(),
+			Began:      txn.began,
+			Age:        time.Since(txn.began),
+			Statements: txn.Stats().Statements,
+			Caller:     caller,
+		}
+		if recent := txn.recentQueries(); len(recent) > 0 {
+			info.LastQuery = recent[len(recent)-1]
+		}
+		infos = append(infos, info)
+	}
+	slices.SortFunc(infos, func(a, b TxInfo) int {
+		return a.Began.Compare(b.Began)
+	})
+	return infos
+}
+
+var registry struct {
+	mu    sync.Mutex
+	nodes map[*TxNode]string
+}
+
+// register adds the node's transaction to the registry if WithRegistry is set.
+func (txn *TxNode) register() {
+	if !txn.cfg.registry {
+		return
+	}
+
+	caller := callerOutside()
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	if registry.nodes == nil {
+		registry.nodes = make(map[*TxNode]string)
+	}
+	registry.nodes[txn] = caller
+}
+
+// untrack removes the node's transaction, which has ended, from the registry
+// and from its Manager.
+func (txn *TxNode) untrack() {
+	txn.cfg.manager.untrack(txn)
+	if !txn.cfg.registry {
+		return
+	}
+
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	delete(registry.nodes, txn)
+}
+
+// pkgPrefix prefixes the names of the functions of this package.
+var pkgPrefix = reflect.TypeFor[TxNode]().PkgPath() + "."
+
+// callerOutside returns the innermost caller that is not a function of this
+// package.
+func callerOutside() string {
+	pcs := make([]uintptr, maxDebugFrames)
+	return firstOutside(pcs[:runtime.Callers(2, pcs)])
+}
+
+// firstOutside returns the first of the callers pcs that is not a function of
+// this package.
+func firstOutside(pcs []uintptr) string {
+	if len(pcs) == 0 {
+		return ""
+	}
+
+	frames := runtime.CallersFrames(pcs)
+	for {
+		f, more := frames.Next()
+		if !strings.HasPrefix(f.Function, pkgPrefix) {
+			return f.Function + " " + f.File + ":" + strconv.Itoa(f.Line)
+		}
+		if !more {
+			return "unknown"
+		}
+	}
+}
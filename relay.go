@@ -0,0 +1,217 @@
+package txnode
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Publisher delivers a batch of outbox events, for example to a message
+// broker. The events are marked delivered only if it returns nil, so it must
+// tolerate redelivery of events it has already published.
+type Publisher func(ctx context.Context, events []Event) error
+
+// Relay delivers the events written to an outbox table. It polls the table in
+// transactions of its own, locking the undelivered rows it reads so that
+// several relays can run side by side, hands them to a Publisher and marks
+// them delivered.
+type Relay struct {
+	db       DB
+	outbox   *Outbox
+	publish  Publisher
+	dialect  Dialect
+	batch    int
+	interval time.Duration
+	retry    RetryPolicy
+	log      Logger
+}
+
+// RelayOption configures a Relay.
+type RelayOption func(*Relay)
+
+// WithBatchSize sets the maximum number of events handed to the publisher at
+// once. The default is 100.
+func WithBatchSize(n int) RelayOption {
+	return func(r *Relay) {
+		r.batch = n
+	}
+}
+
+// WithPollInterval sets the pause between two polls of the outbox table when
+// the previous poll found no events. The default is one second.
+func WithPollInterval(d time.Duration) RelayOption {
+	return func(r *Relay) {
+		r.interval = d
+	}
+}
+
+// WithRelayRetry sets the policy for retrying a failed publication of a batch
+// before leaving it to the next poll. A nil Retryable retries every error.
+func WithRelayRetry(p RetryPolicy) RelayOption {
+	return func(r *Relay) {
+		r.retry = p
+	}
+}
+
+// WithRelayDialect sets the SQL dialect of the outbox database.
+func WithRelayDialect(d Dialect) RelayOption {
+	return func(r *Relay) {
+		r.dialect = d
+	}
+}
+
+// WithRelayLogger sets the logger the relay reports failed polls to.
+func WithRelayLogger(log Logger) RelayOption {
+	return func(r *Relay) {
+		r.log = log
+	}
+}
+
+// NewRelay returns a relay that delivers the events of o, stored in db, to
+// publish.
+func NewRelay(db DB, o *Outbox, publish Publisher, opts ...RelayOption) *Relay {
+	r := &Relay{
+		db:       db,
+		outbox:   o,
+		publish:  publish,
+		batch:    100,
+		interval: time.Second,
+		log:      nopLogger{},
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Run relays events until ctx is done. A failed poll is logged and retried
+// after the poll interval. Run returns nil once ctx is done.
+func (r *Relay) Run(ctx context.Context) error {
+	for {
+		n, err := r.RelayOnce(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			r.log.Log(ctx, slog.LevelError, "relay outbox", slog.Any("error", err))
+		}
+		if n == r.batch && err == nil {
+			continue
+		}
+		if sleep(ctx, r.interval) != nil {
+			return nil
+		}
+	}
+}
+
+// RelayOnce delivers one batch of undelivered events and returns the number
+// of events delivered.
+func (r *Relay) RelayOnce(ctx context.Context) (int, error) {
+	var n int
+	err := Run(ctx, r.db, func(ctx context.Context, txn *TxNode) error {
+		events, err := r.fetch(ctx, txn)
+		if err != nil || len(events) == 0 {
+			return err
+		}
+
+		if err := r.deliver(ctx, events); err != nil {
+			return err
+		}
+
+		if err := r.markDelivered(ctx, txn, events); err != nil {
+			return err
+		}
+		n = len(events)
+		return nil
+	}, WithDialect(r.dialect))
+	return n, err
+}
+
+// fetch reads and locks a batch of undelivered events.
+func (r *Relay) fetch(ctx context.Context, txn *TxNode) ([]Event, error) {
+	rows, err := txn.QueryContext(ctx, r.db, r.selectSQL())
+	if err != nil {
+		return nil, fmt.Errorf("txnode: fetch outbox events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []Event
+	for rows.Next() {
+		var ev Event
+		if err := rows.Scan(&ev.ID, &ev.Topic, &ev.Key, &ev.Payload, &ev.CreatedAt); err != nil {
+			return nil, fmt.Errorf("txnode: fetch outbox events: %w", err)
+		}
+		events = append(events, ev)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("txnode: fetch outbox events: %w", err)
+	}
+	return events, nil
+}
+
+// deliver publishes events, retrying as configured with WithRelayRetry.
+func (r *Relay) deliver(ctx context.Context, events []Event) error {
+	for attempt := 1; ; attempt++ {
+		err := r.publish(ctx, events)
+		if err == nil {
+			return nil
+		}
+		retryable := r.retry.Retryable == nil || r.retry.Retryable(err)
+		if attempt >= r.retry.MaxAttempts || !retryable {
+			return fmt.Errorf("txnode: publish outbox events: %w", err)
+		}
+		if waitErr := r.retry.wait(ctx, attempt); waitErr != nil {
+			return errors.Join(err, waitErr)
+		}
+	}
+}
+
+// markDelivered sets the delivery time of events.
+func (r *Relay) markDelivered(ctx context.Context, txn *TxNode, events []Event) error {
+	s := r.outbox.schema
+	args := make([]any, 0, len(events)+1)
+	args = append(args, time.Now().UTC())
+
+	var b strings.Builder
+	b.WriteString("UPDATE " + s.Table + " SET " + s.DeliveredAt + " = " + r.dialect.placeholder(1))
+	b.WriteString(" WHERE " + s.ID + " IN (")
+	for i, ev := range events {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(r.dialect.placeholder(i + 2))
+		args = append(args, ev.ID)
+	}
+	b.WriteString(")")
+
+	if _, err := txn.ExecContext(ctx, r.db, b.String(), args...); err != nil {
+		return fmt.Errorf("txnode: mark outbox events delivered: %w", err)
+	}
+	return nil
+}
+
+// selectSQL returns the statement reading and locking a batch of undelivered
+// events, oldest first.
+func (r *Relay) selectSQL() string {
+	s := r.outbox.schema
+	cols := strings.Join([]string{s.ID, s.Topic, s.Key, s.Payload, s.CreatedAt}, ", ")
+	limit := strconv.Itoa(r.batch)
+	where := " WHERE " + s.DeliveredAt + " IS NULL ORDER BY " + s.CreatedAt
+
+	switch r.dialect {
+	case DialectSQLServer:
+		return "SELECT TOP " + limit + " " + cols + " FROM " + s.Table +
+			" WITH (UPDLOCK, READPAST, ROWLOCK)" + where
+	case DialectSQLite:
+		// SQLite locks the whole database for writing transactions and has
+		// no row locks to skip.
+		return "SELECT " + cols + " FROM " + s.Table + where + " LIMIT " + limit
+	default:
+		return "SELECT " + cols + " FROM " + s.Table + where + " LIMIT " + limit +
+			" FOR UPDATE SKIP LOCKED"
+	}
+}
@@ -0,0 +1,214 @@
+package txnode
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"sync/atomic"
+)
+
+// Router is a DB that sends reads to read replicas and everything else to the
+// primary. Queries recognized by IsReadQuery go to the replicas in turn;
+// writes, prepared statements and transactions go to the primary, so every
+// statement run in a node's transaction uses the primary. Passing a Router to
+// a nil node thus spreads its non-transactional reads over the replicas.
+type Router struct {
+	primary  DB
+	replicas []DB
+	next     atomic.Uint64
+}
+
+var _ DB = (*Router)(nil)
+
+// NewRouter returns a router over primary and replicas. Without replicas all
+// statements go to the primary.
+func NewRouter(primary DB, replicas ...DB) *Router {
+	return &Router{primary: primary, replicas: replicas}
+}
+
+// Primary returns the primary database.
+func (r *Router) Primary() DB {
+	return r.primary
+}
+
+// Replica returns the next replica in turn, or the primary if there are none.
+func (r *Router) Replica() DB {
+	return pickReplica(r.replicas, &r.next, r.primary)
+}
+
+// BeginTx begins a transaction on the primary.
+func (r *Router) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	return r.primary.BeginTx(ctx, opts)
+}
+
+// PrepareContext prepares a statement on the primary.
+func (r *Router) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return r.primary.PrepareContext(ctx, query)
+}
+
+// ExecContext executes a statement on the primary.
+func (r *Router) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return r.primary.ExecContext(ctx, query, args...)
+}
+
+// QueryContext runs a read query on a replica and any other query on the
+// primary.
+func (r *Router) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return r.route(query).QueryContext(ctx, query, args...)
+}
+
+// QueryRowContext runs a read query on a replica and any other query on the
+// primary.
+func (r *Router) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return r.route(query).QueryRowContext(ctx, query, args...)
+}
+
+func (r *Router) route(query string) DB {
+	if IsReadQuery(query) {
+		return r.Replica()
+	}
+	return r.primary
+}
+
+// WithReplicas sets the read replicas used by QueryReplica and
+// QueryRowReplica while the node has no transaction.
+func WithReplicas(replicas ...DB) Option {
+	return func(c *config) {
+		c.replicas = replicas
+		c.nextReplica = new(atomic.Uint64)
+	}
+}
+
+// QueryReplica runs a read-only query. If the node has not begun its
+// transaction, the query runs on one of the replicas set with WithReplicas,
+// or on db if there are none, without beginning the transaction. Otherwise it
+// runs in the transaction like QueryContext, so that it sees the chain's
+// writes. If txn is nil the query runs on db directly.
+func (txn *TxNode) QueryReplica(ctx context.Context, db DB, query string, args ...any) (*sql.Rows, error) {
+	if txn != nil && txn.tx == nil && txn.isStart {
+		return txn.replica(db).QueryContext(ctx, txn.rebind(query), args...)
+	}
+	return txn.QueryContext(ctx, db, query, args...)
+}
+
+// QueryRowReplica is like QueryReplica for queries expected to return at most
+// one row.
+func (txn *TxNode) QueryRowReplica(ctx context.Context, db DB, query string, args ...any) *Row {
+	if txn != nil && txn.tx == nil && txn.isStart {
+		return &Row{row: txn.replica(db).QueryRowContext(ctx, txn.rebind(query), args...)}
+	}
+	return txn.QueryRowContext(ctx, db, query, args...)
+}
+
+// replica returns the next replica of the node, or db if it has none.
+func (txn *TxNode) replica(db DB) DB {
+	return pickReplica(txn.cfg.replicas, txn.cfg.nextReplica, db)
+}
+
+func pickReplica(replicas []DB, next *atomic.Uint64, fallback DB) DB {
+	if len(replicas) == 0 {
+		return fallback
+	}
+	return replicas[(next.Add(1)-1)%uint64(len(replicas))]
+}
+
+// IsReadQuery reports whether query only reads data, so that it may run on a
+// read replica: a SELECT, VALUES, SHOW or a WITH query without data-modifying
+// statements, that neither locks rows nor selects into a table. A function
+// call may write, as nextval or pg_advisory_lock do, so only the calls of
+// well-known read-only functions, such as count or coalesce, are allowed. The
+// dialect being unknown, query must read data whether or not a backslash
+// escapes quotes in its strings.
+func IsReadQuery(query string) bool {
+	for _, q := range []quoting{{}, conservativeQuoting} {
+		s := sanitizeQuery(query, q)
+		if !isSelectQuery(s) || callsFunction(s) {
+			return false
+		}
+	}
+	return true
+}
+
+// isSelectQuery reports whether the sanitized query q is a query that neither
+// modifies nor locks rows, leaving aside the functions it calls.
+func isSelectQuery(q string) bool {
+	words := strings.Fields(strings.ToUpper(strings.TrimLeft(q, " (")))
+	if len(words) == 0 {
+		return false
+	}
+
+	switch words[0] {
+	case "SELECT", "WITH", "VALUES", "SHOW", "TABLE":
+	default:
+		return false
+	}
+
+	for i, w := range words {
+		w = strings.Trim(w, "(),;")
+		switch w {
+		case "INSERT", "UPDATE", "DELETE", "MERGE", "INTO", "UPSERT":
+			return false
+		case "FOR":
+			if i+1 < len(words) {
+				switch strings.Trim(words[i+1], "(),;") {
+				case "UPDATE", "SHARE", "NO", "KEY":
+					return false
+				}
+			}
+		case "LOCK":
+			return false
+		}
+	}
+	return true
+}
+
+// callsFunction reports whether the sanitized query q calls a function other
+// than the read-only ones of readOnlyFunctions. A name followed by an opening
+// parenthesis is a call unless it is a keyword.
+func callsFunction(q string) bool {
+	for i := 0; i < len(q); i++ {
+		if q[i] != '(' {
+			continue
+		}
+		end := i
+		if end > 0 && q[end-1] == ' ' {
+			end--
+		}
+		start := end
+		for start > 0 && (isIdentByte(q[start-1]) || q[start-1] == '.' || q[start-1] == '"' || q[start-1] == '`') {
+			start--
+		}
+		name := q[start:end]
+		if name == "" || isDigit(name[0]) {
+			continue
+		}
+		if strings.ContainsAny(name, ".\"`") || !readOnlyFunctions[strings.ToUpper(name)] {
+			return true
+		}
+	}
+	return false
+}
+
+// readOnlyFunctions holds the keywords that may precede a parenthesis and the
+// functions and type names known not to write, in upper case.
+var readOnlyFunctions = func() map[string]bool {
+	names := make(map[string]bool)
+	for _, name := range strings.Fields(`
+		SELECT FROM JOIN WHERE AND OR NOT IN EXISTS ANY ALL SOME AS ON USING
+		VALUES UNION INTERSECT EXCEPT OVER FILTER WITHIN LATERAL WITH BY HAVING
+		WHEN THEN ELSE CASE IS LIKE ILIKE BETWEEN ROLLUP CUBE SETS ROW ARRAY
+		TABLE RECURSIVE LIMIT OFFSET DISTINCT
+		COUNT SUM AVG MIN MAX BOOL_AND BOOL_OR ARRAY_AGG STRING_AGG
+		GROUP_CONCAT JSON_AGG JSONB_AGG JSON_BUILD_OBJECT JSONB_BUILD_OBJECT
+		ROW_NUMBER RANK DENSE_RANK LAG LEAD FIRST_VALUE LAST_VALUE
+		COALESCE NULLIF GREATEST LEAST IFNULL ISNULL IF CAST EXTRACT
+		LOWER UPPER LENGTH CHAR_LENGTH SUBSTRING SUBSTR TRIM CONCAT REPLACE
+		ABS ROUND FLOOR CEIL CEILING NOW DATE_TRUNC DATE TO_CHAR UNNEST
+		GENERATE_SERIES
+		NUMERIC DECIMAL VARCHAR CHAR CHARACTER VARYING TIMESTAMP TIME INTERVAL
+		BIT FLOAT
+	`) {
+		names[name] = true
+	}
+	return names
+}()
@@ -0,0 +1,46 @@
+package txnode_test
+
+import (
+	"testing"
+
+	"github.com/MartellOnell/txnode"
+)
+
+func TestIsReadQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{"SELECT 1", true},
+		{"select * from t where id = $1", true},
+		{" (SELECT a FROM t) UNION (SELECT b FROM u)", true},
+		{"-- comment\nSELECT * FROM t WHERE v = 'insert'", true},
+		{"/* c */ WITH a AS (SELECT 1) SELECT * FROM a", true},
+		{"SELECT count(*), max(v), coalesce(sum(v), 0) FROM t WHERE id IN (1, 2)", true},
+		{"SELECT CAST(v AS numeric(10, 2)) FROM t WHERE EXISTS (SELECT 1 FROM u)", true},
+		{"VALUES (1), (2)", true},
+		{"SHOW search_path", true},
+		{"SELECT nextval('s')", false},
+		{"SELECT pg_advisory_lock(1)", false},
+		{"SELECT setval('s', 10)", false},
+		{"SELECT my_schema.refresh_totals()", false},
+		{`SELECT "audit"(1)`, false},
+		{"SELECT * FROM t FOR UPDATE", false},
+		{"SELECT * FROM t FOR NO KEY UPDATE", false},
+		{"SELECT * FROM t LOCK IN SHARE MODE", false},
+		{"SELECT * INTO t2 FROM t", false},
+		{"WITH d AS (DELETE FROM t RETURNING *) SELECT * FROM d", false},
+		// Either reading of the backslash must be a read.
+		{`SELECT * FROM t WHERE p = 'C:\' FOR UPDATE`, false},
+		{`SELECT * FROM t WHERE p = 'it\'s' -- ' FOR UPDATE`, false},
+		{`SELECT * FROM t WHERE p = 'C:\' AND q = 'x'`, true},
+		{"INSERT INTO t VALUES (1)", false},
+		{"UPDATE t SET v = 1", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := txnode.IsReadQuery(tt.query); got != tt.want {
+			t.Errorf("IsReadQuery(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
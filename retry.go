@@ -0,0 +1,163 @@
+package txnode
+
+import (
+	"context"
+	"math/rand/v2"
+	"time"
+)
+
+// RetryPolicy controls how an operation that failed with a retryable error is
+// retried: the whole transaction under Run, see WithRetryPolicy, beginning
+// the transaction, see WithBeginRetry, and single statements, see
+// WithStatementRetry. The pause between two attempts starts at Backoff and is
+// multiplied by Multiplier after every attempt, up to MaxBackoff, then
+// randomized by Jitter.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first one.
+	// Values below 2 disable retries.
+	MaxAttempts int
+	// Backoff is the pause after the first attempt.
+	Backoff time.Duration
+	// MaxBackoff caps the pause between two attempts. Zero means no cap.
+	MaxBackoff time.Duration
+	// Multiplier is the factor the pause grows by after every attempt.
+	// Values up to 1 keep the pause at Backoff.
+	Multiplier float64
+	// Jitter is the fraction, between 0 and 1, by which every pause is
+	// randomly shortened or lengthened, so that transactions that failed
+	// together do not retry in lockstep.
+	Jitter float64
+	// Retryable reports whether err warrants another attempt.
+	// A nil Retryable defers to the node's Classifier, see WithClassifier.
+	Retryable func(err error) bool
+}
+
+// DefaultRetryPolicy returns a policy making up to 3 attempts with an
+// exponential backoff from 50ms to 2s and 20% jitter.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 3,
+		Backoff:     50 * time.Millisecond,
+		MaxBackoff:  2 * time.Second,
+		Multiplier:  2,
+		Jitter:      0.2,
+	}
+}
+
+// shouldRetry reports whether another attempt may follow the given one.
+func (p RetryPolicy) shouldRetry(attempt int, err error, c Classifier) bool {
+	if attempt >= p.MaxAttempts {
+		return false
+	}
+	if p.Retryable == nil {
+		return c.IsRetryable(err)
+	}
+	return p.Retryable(err)
+}
+
+// backoff returns the pause after the given attempt, counting from 1.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	d := float64(p.Backoff)
+	for i := 1; i < attempt && p.Multiplier > 1; i++ {
+		d *= p.Multiplier
+		if p.MaxBackoff > 0 && d >= float64(p.MaxBackoff) {
+			break
+		}
+	}
+	if p.MaxBackoff > 0 {
+		d = min(d, float64(p.MaxBackoff))
+	}
+	if j := min(max(p.Jitter, 0), 1); j > 0 {
+		d *= 1 + j*(2*rand.Float64()-1)
+	}
+	return time.Duration(d)
+}
+
+// wait blocks for the backoff after the given attempt or until ctx is done.
+func (p RetryPolicy) wait(ctx context.Context, attempt int) error {
+	return sleep(ctx, p.backoff(attempt))
+}
+
+// WithBeginRetry retries beginning the transaction under p when it fails with
+// an error p deems retryable. No statement has run yet when begin fails, so a
+// nil Retryable in p retries broken connections, such as driver.ErrBadConn,
+// Postgres class 08 errors and MySQL errors 2006 and 2013, on top of the
+// errors the Classifier deems retryable.
+func WithBeginRetry(p RetryPolicy) Option {
+	return func(c *config) {
+		c.beginRetry = p
+	}
+}
+
+// beginRetryPolicy returns the policy set with WithBeginRetry, with its
+// default Retryable.
+func (txn *TxNode) beginRetryPolicy() RetryPolicy {
+	p := txn.cfg.beginRetry
+	if p.Retryable == nil {
+		c := txn.cfg.classifier
+		p.Retryable = func(err error) bool {
+			return c.IsRetryable(err) || c.Classify(err) == CategoryConnection
+		}
+	}
+	return p
+}
+
+// WithStatementRetry retries every statement prepared or executed through the
+// node under p when it fails with an error p deems retryable. Most databases
+// abort the transaction, or at least the statement's effects, on errors such
+// as serialization failures and deadlocks, so retrying a single statement is
+// only sound for errors that leave the transaction usable. A nil Retryable in
+// p therefore retries SQLite busy errors only, rather than deferring to the
+// Classifier.
+func WithStatementRetry(p RetryPolicy) Option {
+	return func(c *config) {
+		if p.Retryable == nil {
+			p.Retryable = IsBusy
+		}
+		c.stmtRetry = p
+	}
+}
+
+// retry calls op until it succeeds or fails with an error that neither p nor
+// the busy retry policy set with WithSQLiteBusyRetry retries, counting the
+// retries in the statistics.
+func (txn *TxNode) retry(ctx context.Context, p RetryPolicy, op func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := op()
+		if err == nil {
+			return nil
+		}
+
+		var d time.Duration
+		switch {
+		case p.shouldRetry(attempt, err, txn.cfg.classifier):
+			d = p.backoff(attempt)
+		case txn.isBusyRetryable(attempt, err):
+			d = txn.cfg.busyRetry.backoff(attempt)
+		default:
+			return err
+		}
+		if sleep(ctx, d) != nil {
+			return err
+		}
+		txn.countRetry()
+	}
+}
+
+// sleep blocks for d or until ctx is done, returning the context's error in
+// the latter case.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,219 @@
+package txnode_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+
+	"github.com/MartellOnell/txnode"
+)
+
+// flakyDB fails the first failures calls to BeginTx with err.
+type flakyDB struct {
+	*sql.DB
+	err      error
+	failures int
+	begins   int
+}
+
+func (db *flakyDB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	db.begins++
+	if db.begins <= db.failures {
+		return nil, db.err
+	}
+	return db.DB.BeginTx(ctx, opts)
+}
+
+// pgError is an error carrying a Postgres SQLSTATE code.
+type pgError string
+
+func (e pgError) Error() string    { return "pg error " + string(e) }
+func (e pgError) SQLState() string { return string(e) }
+
+// MySQLError mirrors the error of go-sql-driver/mysql.
+type MySQLError struct {
+	Number  uint16
+	Message string
+}
+
+func (e *MySQLError) Error() string { return fmt.Sprintf("Error %d: %s", e.Number, e.Message) }
+
+func TestBeginRetryConnectionErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		err   error
+		retry bool
+	}{
+		{"bad connection", fmt.Errorf("begin: %w", driver.ErrBadConn), true},
+		{"postgres connection failure", pgError("08006"), true},
+		{"postgres serialization failure", pgError("40001"), true},
+		{"mysql server gone", &MySQLError{Number: 2006, Message: "MySQL server has gone away"}, true},
+		{"mysql connection lost", &MySQLError{Number: 2013, Message: "Lost connection to MySQL server"}, true},
+		{"postgres syntax error", pgError("42601"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.retry {
+				mock.ExpectBegin()
+				mock.ExpectCommit()
+			}
+			db := &flakyDB{DB: conn, err: tt.err, failures: 1}
+
+			txn := txnode.New(txnode.WithBeginRetry(txnode.RetryPolicy{MaxAttempts: 2}))
+			txn.SetEnd()
+			err = txn.Begin(context.Background(), db)
+			if tt.retry {
+				if err != nil {
+					t.Fatalf("Begin() = %v, want a retried begin", err)
+				}
+				if err := txn.CommitIfNeeded(); err != nil {
+					t.Fatal(err)
+				}
+			} else if !errors.Is(err, tt.err) {
+				t.Fatalf("Begin() = %v, want %v", err, tt.err)
+			}
+			if want := map[bool]int{false: 1, true: 2}[tt.retry]; db.begins != want {
+				t.Errorf("%d begins, want %d", db.begins, want)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestBeginRetryBackoff(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy txnode.RetryPolicy
+		// min is the least total pause before the last attempt.
+		min time.Duration
+	}{
+		{"constant", txnode.RetryPolicy{MaxAttempts: 4, Backoff: 10 * time.Millisecond}, 30 * time.Millisecond},
+		{"exponential", txnode.RetryPolicy{MaxAttempts: 4, Backoff: 10 * time.Millisecond, Multiplier: 2}, 70 * time.Millisecond},
+		{"capped", txnode.RetryPolicy{MaxAttempts: 4, Backoff: 10 * time.Millisecond, Multiplier: 4, MaxBackoff: 15 * time.Millisecond}, 40 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			mock.ExpectBegin()
+			mock.ExpectRollback()
+			db := &flakyDB{DB: conn, err: driver.ErrBadConn, failures: tt.policy.MaxAttempts - 1}
+
+			txn := txnode.New(txnode.WithBeginRetry(tt.policy))
+			start := time.Now()
+			if err := txn.Begin(context.Background(), db); err != nil {
+				t.Fatal(err)
+			}
+			if elapsed := time.Since(start); elapsed < tt.min {
+				t.Errorf("begin took %v, want at least %v", elapsed, tt.min)
+			}
+			if got := txn.Stats().Retries; got != tt.policy.MaxAttempts-1 {
+				t.Errorf("Stats().Retries = %d, want %d", got, tt.policy.MaxAttempts-1)
+			}
+			if err := txn.RollbackTransaction(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestRunRetryPolicy(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		policy   txnode.RetryPolicy
+		attempts int
+	}{
+		{"serialization failure", pgError("40001"), txnode.RetryPolicy{MaxAttempts: 3}, 3},
+		{"deadlock", &MySQLError{Number: 1213}, txnode.RetryPolicy{MaxAttempts: 2}, 2},
+		{"not retryable", pgError("23505"), txnode.RetryPolicy{MaxAttempts: 3}, 1},
+		{"custom retryable", pgError("23505"), txnode.RetryPolicy{
+			MaxAttempts: 3,
+			Retryable:   func(err error) bool { return true },
+		}, 3},
+		{"retries disabled", pgError("40001"), txnode.RetryPolicy{MaxAttempts: 1}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+			for range tt.attempts {
+				mock.ExpectBegin()
+				mock.ExpectRollback()
+			}
+
+			attempts := 0
+			err = txnode.Run(context.Background(), db, func(context.Context, *txnode.TxNode) error {
+				attempts++
+				return tt.err
+			}, txnode.WithRetryPolicy(tt.policy))
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Run() = %v, want %v", err, tt.err)
+			}
+			if attempts != tt.attempts {
+				t.Errorf("fn ran %d times, want %d", attempts, tt.attempts)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestStatementRetry(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		retried bool
+	}{
+		{"busy", errors.New("database is locked"), true},
+		{"serialization failure", pgError("40001"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+			mock.ExpectBegin()
+			mock.ExpectExec("UPDATE t SET v = 1").WillReturnError(tt.err)
+			if tt.retried {
+				mock.ExpectExec("UPDATE t SET v = 1").WillReturnResult(sqlmock.NewResult(0, 1))
+			}
+			mock.ExpectRollback()
+
+			txn := txnode.New(txnode.WithStatementRetry(txnode.RetryPolicy{MaxAttempts: 2}))
+			_, err = txn.ExecContext(context.Background(), db, "UPDATE t SET v = 1")
+			if tt.retried && err != nil {
+				t.Fatalf("ExecContext() = %v, want the retry to succeed", err)
+			}
+			if !tt.retried && !errors.Is(err, tt.err) {
+				t.Fatalf("ExecContext() = %v, want %v", err, tt.err)
+			}
+			if err := txn.RollbackTransaction(); err != nil {
+				t.Fatal(err)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
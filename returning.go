@@ -0,0 +1,45 @@
+package txnode
+
+import (
+	"context"
+	"fmt"
+)
+
+// ExecReturning runs query, an INSERT, UPDATE or DELETE with a RETURNING
+// clause (OUTPUT on SQL Server), through txn like Get and scans the returned
+// rows into a slice of T, mapping the columns as ScanRow does. MySQL has no
+// RETURNING clause; use InsertID there to read generated keys.
+func ExecReturning[T any](ctx context.Context, txn *TxNode, db DB, query string, args ...any) ([]T, error) {
+	return Select[T](ctx, txn, db, query, args...)
+}
+
+// InsertID runs query, an INSERT of a single row, in the node's transaction,
+// beginning it on db if needed, and returns the value generated for the
+// integer column idColumn. The way to read it depends on the dialect: Postgres
+// and SQLite append RETURNING idColumn to query, SQL Server selects
+// SCOPE_IDENTITY() after it and MySQL reads the last insert id reported by the
+// driver, so query must not have a RETURNING clause itself.
+func (txn *TxNode) InsertID(ctx context.Context, db DB, query, idColumn string, args ...any) (int64, error) {
+	if !isIdentifier(idColumn) {
+		return 0, fmt.Errorf("txnode: invalid column %q", idColumn)
+	}
+
+	var id int64
+	switch txn.dialect() {
+	case DialectMySQL:
+		res, err := txn.ExecContext(ctx, db, query, args...)
+		if err != nil {
+			return 0, err
+		}
+		return res.LastInsertId()
+	case DialectSQLServer:
+		query += "; SELECT CONVERT(bigint, SCOPE_IDENTITY())"
+	default:
+		query += " RETURNING " + idColumn
+	}
+
+	if err := txn.QueryRowContext(ctx, db, query, args...).Scan(&id); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
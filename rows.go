@@ -0,0 +1,66 @@
+package txnode
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// ErrUnclosedRows is returned by CommitIfNeeded on a node created with
+// WithUnclosedRowsCheck(true) when a result set is still open.
+var ErrUnclosedRows = errors.New("unclosed rows")
+
+// openRows is a result set of QueryContext with the query it came from.
+type openRows struct {
+	rows  *sql.Rows
+	query string
+}
+
+// WithUnclosedRowsCheck tracks the *sql.Rows returned by QueryContext, and by
+// the helpers built on it, and logs a warning with the originating queries if
+// any of them is still open when the transaction is committed or rolled back.
+// Open result sets keep the connection busy, which fails the commit on some
+// drivers, such as MySQL's with "busy buffer". With fail, committing with
+// open rows rolls the transaction back and fails with an error wrapping
+// ErrUnclosedRows; a rollback only warns. Warnings go to the logger set with
+// WithLogger.
+func WithUnclosedRowsCheck(fail bool) Option {
+	return func(c *config) {
+		c.rowsCheck = true
+		c.rowsCheckFail = fail
+	}
+}
+
+// trackRows records a result set for WithUnclosedRowsCheck.
+func (txn *TxNode) trackRows(rows *sql.Rows, query string) {
+	if !txn.cfg.rowsCheck {
+		return
+	}
+	root := txn.root()
+	root.openRows = append(root.openRows, openRows{rows: rows, query: query})
+}
+
+// checkOpenRows reports the result sets that are still open as the
+// transaction ends, returning an error if the commit must fail.
+func (txn *TxNode) checkOpenRows(commit bool) error {
+	var queries []string
+	for _, r := range txn.openRows {
+		// Columns fails once the rows are closed, which Next does after the
+		// last row.
+		if _, err := r.rows.Columns(); err == nil {
+			queries = append(queries, txn.sanitize(r.query))
+		}
+	}
+	txn.openRows = nil
+	if len(queries) == 0 {
+		return nil
+	}
+
+	txn.log(slog.LevelWarn, "unclosed rows", slog.Any("queries", queries))
+	if commit && txn.cfg.rowsCheckFail {
+		return fmt.Errorf("%w: %s", ErrUnclosedRows, strings.Join(queries, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package txnode
+
+import (
+	"context"
+	"errors"
+	"runtime/debug"
+)
+
+// Run begins a transaction on db and calls fn with a node bound to it.
+// The context passed to fn carries the node, see FromContext.
+// The transaction is committed if fn returns nil and rolled back if fn returns
+// an error or panics; a panic is re-raised once the rollback has been done,
+// unless the node is configured with WithRecovery.
+// fn must not commit the node itself.
+//
+// If the node is configured with a RetryPolicy, a failed attempt whose error
+// is retryable runs fn again in a new transaction, as does a failure with
+// ErrStaleVersion under WithStaleRetry.
+func Run(
+	ctx context.Context,
+	db DB,
+	fn func(ctx context.Context, txn *TxNode) error,
+	opts ...Option,
+) error {
+	for attempt := 1; ; attempt++ {
+		txn := New(opts...)
+		txn.stats.Retries = attempt - 1
+		err := txn.run(ctx, db, fn)
+
+		if err == nil {
+			return nil
+		}
+
+		var waitErr error
+		switch {
+		case txn.cfg.retry.shouldRetry(attempt, err, txn.cfg.classifier):
+			waitErr = txn.cfg.retry.wait(ctx, attempt)
+		case txn.isBusyRetryable(attempt, err):
+			waitErr = txn.cfg.busyRetry.wait(ctx, attempt)
+		case attempt < txn.cfg.staleRetry && errors.Is(err, ErrStaleVersion):
+			waitErr = ctx.Err()
+		default:
+			return err
+		}
+		if waitErr != nil {
+			return err
+		}
+	}
+}
+
+func (txn *TxNode) run(
+	ctx context.Context,
+	db DB,
+	fn func(ctx context.Context, txn *TxNode) error,
+) (err error) {
+	if err := txn.begin(ctx, db); err != nil {
+		return err
+	}
+
+	defer func() {
+		p := recover()
+		if p == nil {
+			return
+		}
+
+		panicErr := &PanicError{Value: p, Stack: debug.Stack()}
+		_ = txn.rollback(panicErr)
+		if !txn.cfg.recoverPanics {
+			panic(p)
+		}
+		err = panicErr
+	}()
+
+	err = txn.profile(ctx, "", func(ctx context.Context) error {
+		return fn(NewContext(ctx, txn), txn)
+	})
+	if err != nil {
+		if rollbackErr := txn.rollback(err); rollbackErr != nil {
+			return errors.Join(err, rollbackErr)
+		}
+		return err
+	}
+
+	txn.SetEnd()
+	return txn.CommitIfNeeded()
+}
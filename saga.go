@@ -0,0 +1,115 @@
+package txnode
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Saga runs steps that mix database work with external calls that cannot be
+// rolled back with the transaction. Each step may come with a compensation
+// that undoes its external effects. The database work of all steps runs in
+// one transaction; if a step fails or the commit fails, the transaction is
+// rolled back and then the compensations of the steps that had completed run
+// in reverse order. The compensation of the failing step itself does not run.
+type Saga struct {
+	opts  []Option
+	steps []sagaStep
+}
+
+type sagaStep struct {
+	name       string
+	action     func(ctx context.Context, txn *TxNode) error
+	compensate func(ctx context.Context) error
+}
+
+// CompensationError reports the failure of the compensation of a saga step.
+type CompensationError struct {
+	Step string
+	Err  error
+}
+
+func (e *CompensationError) Error() string {
+	return fmt.Sprintf("txnode: compensate %s: %v", e.Step, e.Err)
+}
+
+func (e *CompensationError) Unwrap() error {
+	return e.Err
+}
+
+// NewSaga returns an empty saga whose transaction is configured with opts.
+func NewSaga(opts ...Option) *Saga {
+	return &Saga{opts: opts}
+}
+
+// Step appends a step. compensate may be nil for steps whose effects are
+// limited to the transaction.
+func (s *Saga) Step(
+	name string,
+	action func(ctx context.Context, txn *TxNode) error,
+	compensate func(ctx context.Context) error,
+) *Saga {
+	s.steps = append(s.steps, sagaStep{name: name, action: action, compensate: compensate})
+	return s
+}
+
+// Run runs the steps in one transaction begun on db, as Run does for a single
+// function. On failure it returns the step or commit error joined with the
+// CompensationErrors of the compensations that failed. Compensations run with
+// a context that is not canceled together with ctx.
+//
+// If the saga is configured with a RetryPolicy, the compensations run after
+// every failed attempt. Failed compensations of an attempt that was followed
+// by a successful one are reported in the error returned despite the commit.
+func (s *Saga) Run(ctx context.Context, db DB) error {
+	var (
+		mu       sync.Mutex
+		compErrs []error
+	)
+
+	err := Run(ctx, db, func(ctx context.Context, txn *TxNode) error {
+		var done []sagaStep
+		txn.OnRollback(func(error) {
+			mu.Lock()
+			defer mu.Unlock()
+			compErrs = append(compErrs, compensate(context.WithoutCancel(ctx), done)...)
+		})
+
+		for _, st := range s.steps {
+			if err := st.action(ctx, txn); err != nil {
+				return fmt.Errorf("%s: %w", st.name, err)
+			}
+			mu.Lock()
+			done = append(done, st)
+			mu.Unlock()
+		}
+		return nil
+	}, s.opts...)
+
+	mu.Lock()
+	defer mu.Unlock()
+	switch {
+	case err != nil:
+		return errors.Join(append([]error{err}, compErrs...)...)
+	case len(compErrs) > 0:
+		return fmt.Errorf("txnode: saga committed after a failed attempt: %w", errors.Join(compErrs...))
+	default:
+		return nil
+	}
+}
+
+// compensate runs the compensations of steps in reverse order.
+func compensate(ctx context.Context, steps []sagaStep) []error {
+	var errs []error
+	for i := len(steps) - 1; i >= 0; i-- {
+		st := steps[i]
+		if st.compensate == nil {
+			continue
+		}
+		if err := st.compensate(ctx); err != nil {
+			errs = append(errs, &CompensationError{Step: st.name, Err: err})
+		}
+	}
+	return errs
+}
@@ -0,0 +1,210 @@
+package txnode
+
+import "strings"
+
+// SanitizeQuery returns query with string and numeric literals replaced by ?,
+// comments removed and runs of whitespace collapsed, so it can be logged or
+// traced without leaking the values embedded in it. Bind placeholders such as
+// $1 are kept.
+//
+// Not knowing the dialect, it reads query conservatively: a backslash escapes
+// the next character in every string, # starts a comment as in MySQL and
+// double-quoted text is replaced as a string, even where it is an identifier.
+// Use SanitizeQueryDialect to keep the identifiers of a known dialect.
+func SanitizeQuery(query string) string {
+	return sanitizeQuery(query, conservativeQuoting)
+}
+
+// SanitizeQueryDialect is like SanitizeQuery but reads query with the string,
+// identifier and comment syntax of dialect d. A Postgres double-quoted
+// identifier is kept, and a backslash only escapes in an E'...' string.
+func SanitizeQueryDialect(d Dialect, query string) string {
+	return sanitizeQuery(query, d.quoting())
+}
+
+// quoting describes how a dialect writes strings, identifiers and comments.
+type quoting struct {
+	// backslash reports whether a backslash escapes the next character in
+	// every quoted string, and not only in a Postgres E'...' string.
+	backslash bool
+	// doubleQuoted reports whether double-quoted text may be a string
+	// rather than an identifier.
+	doubleQuoted bool
+	// hashComments reports whether # starts a comment running to the end of
+	// the line.
+	hashComments bool
+}
+
+// conservativeQuoting reads a query of an unknown dialect so that no literal
+// is left out.
+var conservativeQuoting = quoting{backslash: true, doubleQuoted: true, hashComments: true}
+
+func (d Dialect) quoting() quoting {
+	switch d {
+	case DialectPostgres:
+		return quoting{}
+	case DialectMySQL:
+		return quoting{backslash: true, doubleQuoted: true, hashComments: true}
+	default:
+		// SQLite falls back to a string for a double-quoted name that is
+		// not a column, and SQL Server reads one as a string with
+		// QUOTED_IDENTIFIER off.
+		return quoting{doubleQuoted: true}
+	}
+}
+
+func sanitizeQuery(query string, q quoting) string {
+	var b strings.Builder
+	b.Grow(len(query))
+
+	space := false
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			space = true
+			i++
+			continue
+		case isCommentStart(query, i, q):
+			i = skipComment(query, i)
+			space = true
+			continue
+		case c == '\'' || c == '"' && q.doubleQuoted:
+			i = skipString(query, i, c, q.backslash)
+			writeToken(&b, &space, "?")
+			continue
+		case c == '"' || c == '`':
+			end := skipQuoted(query, i, c)
+			writeToken(&b, &space, query[i:end])
+			i = end
+			continue
+		case c == '$' && i+1 < len(query) && !isDigit(query[i+1]):
+			if end, ok := skipDollarQuoted(query, i); ok {
+				i = end
+				writeToken(&b, &space, "?")
+				continue
+			}
+		case isStringPrefix(query, i):
+			i = skipString(query, i+1, '\'', q.backslash || c == 'E' || c == 'e')
+			writeToken(&b, &space, "?")
+			continue
+		case c == '$' || isIdentByte(c) && !isDigit(c):
+			j := i + 1
+			for j < len(query) && isIdentByte(query[j]) {
+				j++
+			}
+			writeToken(&b, &space, query[i:j])
+			i = j
+			continue
+		case isDigit(c):
+			j := i + 1
+			for j < len(query) && (isDigit(query[j]) || query[j] == '.') {
+				j++
+			}
+			writeToken(&b, &space, "?")
+			i = j
+			continue
+		}
+
+		writeToken(&b, &space, query[i:i+1])
+		i++
+	}
+
+	return b.String()
+}
+
+func writeToken(b *strings.Builder, space *bool, tok string) {
+	if *space && b.Len() > 0 {
+		b.WriteByte(' ')
+	}
+	*space = false
+	b.WriteString(tok)
+}
+
+// isStringPrefix reports whether the string starting at i+1 is prefixed with
+// one of the letters E, N, X or B making it an escape, national, hex or bit
+// string.
+func isStringPrefix(s string, i int) bool {
+	if i+1 >= len(s) || s[i+1] != '\'' || i > 0 && isIdentByte(s[i-1]) {
+		return false
+	}
+	switch s[i] {
+	case 'E', 'e', 'N', 'n', 'X', 'x', 'B', 'b':
+		return true
+	}
+	return false
+}
+
+// isCommentStart reports whether a comment starts at i.
+func isCommentStart(s string, i int, q quoting) bool {
+	return strings.HasPrefix(s[i:], "--") || strings.HasPrefix(s[i:], "/*") || q.hashComments && s[i] == '#'
+}
+
+// skipComment returns the index just past the comment starting at i: the
+// newline ending a -- or # comment, or the */ closing a /* comment.
+func skipComment(s string, i int) int {
+	if strings.HasPrefix(s[i:], "/*") {
+		if end := strings.Index(s[i+2:], "*/"); end >= 0 {
+			return i + 2 + end + 2
+		}
+		return len(s)
+	}
+	return lineEnd(s, i)
+}
+
+// skipString returns the index just past the string starting at i, treating
+// a doubled quote and, if backslash is set, a backslash followed by any
+// character as escaped ones.
+func skipString(s string, i int, quote byte, backslash bool) int {
+	for j := i + 1; j < len(s); j++ {
+		switch {
+		case backslash && s[j] == '\\':
+			j++
+		case s[j] != quote:
+		case j+1 < len(s) && s[j+1] == quote:
+			j++
+		default:
+			return j + 1
+		}
+	}
+	return len(s)
+}
+
+// skipQuoted returns the index just past the quoted section starting at i,
+// treating a doubled quote as an escaped one.
+func skipQuoted(s string, i int, quote byte) int {
+	return skipString(s, i, quote, false)
+}
+
+// skipDollarQuoted returns the index just past the Postgres dollar-quoted
+// string starting at i, and false if there is none.
+func skipDollarQuoted(s string, i int) (int, bool) {
+	j := i + 1
+	for j < len(s) && isIdentByte(s[j]) {
+		j++
+	}
+	if j >= len(s) || s[j] != '$' {
+		return 0, false
+	}
+
+	tag := s[i : j+1]
+	end := strings.Index(s[j+1:], tag)
+	if end < 0 {
+		return len(s), true
+	}
+	return j + 1 + end + len(tag), true
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || isDigit(c)
+}
+
+// sanitize returns query sanitized for the node's dialect, see
+// SanitizeQueryDialect.
+func (txn *TxNode) sanitize(query string) string {
+	return SanitizeQueryDialect(txn.dialect(), query)
+}
@@ -0,0 +1,60 @@
+package txnode_test
+
+import (
+	"testing"
+
+	"github.com/MartellOnell/txnode"
+)
+
+func TestSanitizeQuery(t *testing.T) {
+	tests := []struct {
+		name, query, want string
+	}{
+		{"numbers", "SELECT * FROM t WHERE id = 42 AND v > 1.5", "SELECT * FROM t WHERE id = ? AND v > ?"},
+		{"string", "UPDATE t SET v = 'secret' WHERE id = $1", "UPDATE t SET v = ? WHERE id = $1"},
+		{"doubled quote", "SELECT 'it''s hunter2'", "SELECT ?"},
+		{"backslash escape", `SELECT * FROM t WHERE name = 'O\'Brien secret'`, "SELECT * FROM t WHERE name = ?"},
+		{"escape string", `SELECT E'it\'s hunter2'`, "SELECT ?"},
+		{"national string", "SELECT N'hunter2'", "SELECT ?"},
+		{"double-quoted string", `SELECT * FROM users WHERE email = "bob@example.com"`, "SELECT * FROM users WHERE email = ?"},
+		{"dollar-quoted", "SELECT $tag$hunter2$tag$", "SELECT ?"},
+		{"line comment", "SELECT 1 -- token abc123secret\nFROM t", "SELECT ? FROM t"},
+		{"block comment", "SELECT /* token abc123secret */ 1", "SELECT ?"},
+		{"hash comment", "SELECT 1 # token abc123secret", "SELECT ?"},
+		{"backtick identifier", "SELECT `it's` FROM t", "SELECT `it's` FROM t"},
+		{"whitespace", "SELECT\n\t1\r\n  FROM   t", "SELECT ? FROM t"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := txnode.SanitizeQuery(tt.query); got != tt.want {
+				t.Errorf("SanitizeQuery(%q) = %q, want %q", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSanitizeQueryDialect(t *testing.T) {
+	tests := []struct {
+		name    string
+		dialect txnode.Dialect
+		query   string
+		want    string
+	}{
+		{"postgres identifier", txnode.DialectPostgres, `SELECT "userName" FROM "Users" WHERE id = 1`, `SELECT "userName" FROM "Users" WHERE id = ?`},
+		{"postgres standard string", txnode.DialectPostgres, `SELECT 'C:\' AS dir, 'hunter2'`, "SELECT ? AS dir, ?"},
+		{"postgres escape string", txnode.DialectPostgres, `SELECT E'it\'s hunter2'`, "SELECT ?"},
+		{"postgres comment", txnode.DialectPostgres, "SELECT 1 -- token abc123secret", "SELECT ?"},
+		{"mysql double-quoted", txnode.DialectMySQL, `SELECT * FROM users WHERE email = "bob@example.com"`, "SELECT * FROM users WHERE email = ?"},
+		{"mysql backslash", txnode.DialectMySQL, `SELECT 'O\'Brien secret', "say \"hunter2\""`, "SELECT ?, ?"},
+		{"mysql hash comment", txnode.DialectMySQL, "SELECT 1 # token abc123secret\nFROM t", "SELECT ? FROM t"},
+		{"sqlite double-quoted", txnode.DialectSQLite, `SELECT "hunter2"`, "SELECT ?"},
+		{"sqlserver comment", txnode.DialectSQLServer, "SELECT N'hunter2' /* abc123secret */", "SELECT ?"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := txnode.SanitizeQueryDialect(tt.dialect, tt.query); got != tt.want {
+				t.Errorf("SanitizeQueryDialect(%v, %q) = %q, want %q", tt.dialect, tt.query, got, tt.want)
+			}
+		})
+	}
+}
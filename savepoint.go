@@ -0,0 +1,86 @@
+package txnode
+
+import (
+	"context"
+	"fmt"
+)
+
+// Savepoint creates a savepoint with the given name inside the active transaction.
+func (txn *TxNode) Savepoint(ctx context.Context, name string) error {
+	return txn.execSavepoint(ctx, name, savepointSQL)
+}
+
+// RollbackToSavepoint rolls the transaction back to the named savepoint,
+// undoing the work done after it while keeping the transaction open.
+func (txn *TxNode) RollbackToSavepoint(ctx context.Context, name string) error {
+	return txn.execSavepoint(ctx, name, rollbackToSavepointSQL)
+}
+
+// ReleaseSavepoint releases the named savepoint, keeping the work done after it.
+func (txn *TxNode) ReleaseSavepoint(ctx context.Context, name string) error {
+	return txn.execSavepoint(ctx, name, releaseSavepointSQL)
+}
+
+func (txn *TxNode) execSavepoint(
+	ctx context.Context,
+	name string,
+	build func(Dialect, string) string,
+) error {
+	if txn == nil || txn.tx == nil {
+		return ErrNotStarted
+	}
+
+	if !isIdentifier(name) {
+		return fmt.Errorf("%w: %q", ErrInvalidSavepointName, name)
+	}
+
+	query := build(txn.cfg.dialect, name)
+	if query == "" {
+		return nil
+	}
+
+	_, err := txn.tx.ExecContext(ctx, query)
+	return err
+}
+
+func savepointSQL(d Dialect, name string) string {
+	if d == DialectSQLServer {
+		return "SAVE TRANSACTION " + name
+	}
+	return "SAVEPOINT " + name
+}
+
+func rollbackToSavepointSQL(d Dialect, name string) string {
+	if d == DialectSQLServer {
+		return "ROLLBACK TRANSACTION " + name
+	}
+	return "ROLLBACK TO SAVEPOINT " + name
+}
+
+// releaseSavepointSQL returns an empty query for SQL Server, which has no
+// statement to release a savepoint.
+func releaseSavepointSQL(d Dialect, name string) string {
+	if d == DialectSQLServer {
+		return ""
+	}
+	return "RELEASE SAVEPOINT " + name
+}
+
+// isIdentifier reports whether name is a plain SQL identifier that is safe to
+// interpolate into a statement.
+func isIdentifier(name string) bool {
+	if name == "" {
+		return false
+	}
+
+	for i, r := range name {
+		switch {
+		case r == '_', r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z':
+		case r >= '0' && r <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+
+	return true
+}
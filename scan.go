@@ -0,0 +1,270 @@
+package txnode
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"iter"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Get runs query through txn, beginning its transaction on db if needed, and
+// scans the first row into a T, mapping the columns as ScanRow does. Get
+// returns sql.ErrNoRows if the query returns no rows. If txn is nil the query
+// runs on db directly.
+func Get[T any](ctx context.Context, txn *TxNode, db DB, query string, args ...any) (T, error) {
+	var zero T
+	rows, err := txn.QueryContext(ctx, db, query, args...)
+	if err != nil {
+		return zero, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return zero, err
+		}
+		return zero, sql.ErrNoRows
+	}
+
+	v, err := scanOne[T](rows)
+	if err != nil {
+		return zero, err
+	}
+	return v, rows.Close()
+}
+
+// Select runs query through txn like Get and scans all rows into a slice of T.
+func Select[T any](ctx context.Context, txn *TxNode, db DB, query string, args ...any) ([]T, error) {
+	rows, err := txn.QueryContext(ctx, db, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return ScanAll[T](rows)
+}
+
+// QueryIter runs query through txn like Get and returns an iterator over its
+// rows, each scanned into a T. The query runs when the iteration starts, and
+// the rows are closed when it ends, including when the loop is left early:
+//
+//	for v, err := range txnode.QueryIter[Order](ctx, txn, db, query) {
+//		if err != nil {
+//			return err
+//		}
+//		...
+//	}
+//
+// An error running the query, scanning a row or iterating the rows is yielded
+// once, with the zero T, and ends the iteration.
+func QueryIter[T any](ctx context.Context, txn *TxNode, db DB, query string, args ...any) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+		rows, err := txn.QueryContext(ctx, db, query, args...)
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+		defer rows.Close()
+
+		cols, err := rows.Columns()
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+
+		for rows.Next() {
+			var v T
+			if err := scanInto(rows, cols, reflect.ValueOf(&v).Elem()); err != nil {
+				yield(zero, err)
+				return
+			}
+			if !yield(v, nil) {
+				return
+			}
+		}
+		if err := rows.Err(); err != nil {
+			yield(zero, err)
+		}
+	}
+}
+
+// ScanRow scans the current row of rows into dest, which must be a pointer.
+// A struct is filled field by field: the columns are matched to its exported
+// fields by the field's db tag or, without a tag, by its name compared
+// case-insensitively. Fields of embedded structs are matched as if they were
+// fields of the outer struct, and nil pointers to embedded structs are
+// allocated as needed, except for pointers to unexported types, which are
+// ignored. Pointer fields are set to nil for NULL columns. A db
+// tag of "-" excludes a field. Other types, and structs implementing
+// sql.Scanner, are scanned from a single column.
+//
+// The mapping of every struct type is computed once and cached.
+func ScanRow(rows *sql.Rows, dest any) error {
+	rv := reflect.ValueOf(dest)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return fmt.Errorf("txnode: scan into non-pointer %T", dest)
+	}
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	return scanInto(rows, cols, rv.Elem())
+}
+
+// ScanAll scans all remaining rows of rows into a slice of T, as ScanRow does
+// for one row, and closes rows.
+func ScanAll[T any](rows *sql.Rows) ([]T, error) {
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	var out []T
+	for rows.Next() {
+		var v T
+		if err := scanInto(rows, cols, reflect.ValueOf(&v).Elem()); err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+	}
+	return out, rows.Err()
+}
+
+// scanOne scans the current row of rows into a T.
+func scanOne[T any](rows *sql.Rows) (T, error) {
+	var v T
+	cols, err := rows.Columns()
+	if err != nil {
+		return v, err
+	}
+	return v, scanInto(rows, cols, reflect.ValueOf(&v).Elem())
+}
+
+// scanInto scans the current row of rows, with columns cols, into rv.
+func scanInto(rows *sql.Rows, cols []string, rv reflect.Value) error {
+	if rv.Kind() == reflect.Pointer && !isScalar(rv.Type()) && rv.Type().Elem().Kind() == reflect.Struct {
+		if rv.IsNil() {
+			rv.Set(reflect.New(rv.Type().Elem()))
+		}
+		rv = rv.Elem()
+	}
+
+	if isScalar(rv.Type()) {
+		if len(cols) != 1 {
+			return fmt.Errorf("txnode: scan %d columns into %s", len(cols), rv.Type())
+		}
+		return rows.Scan(rv.Addr().Interface())
+	}
+
+	m := structMapOf(rv.Type())
+	dest := make([]any, len(cols))
+	for i, col := range cols {
+		path, ok := m[strings.ToLower(col)]
+		if !ok {
+			return fmt.Errorf("txnode: no field of %s for column %q", rv.Type(), col)
+		}
+		dest[i] = fieldByPath(rv, path).Addr().Interface()
+	}
+	return rows.Scan(dest...)
+}
+
+var (
+	scannerType = reflect.TypeFor[sql.Scanner]()
+	timeType    = reflect.TypeFor[time.Time]()
+)
+
+// isScalar reports whether values of t are scanned from a single column
+// rather than mapped field by field.
+func isScalar(t reflect.Type) bool {
+	if t.Implements(scannerType) || reflect.PointerTo(t).Implements(scannerType) {
+		return true
+	}
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t.Kind() != reflect.Struct || t == timeType ||
+		t.Implements(scannerType) || reflect.PointerTo(t).Implements(scannerType)
+}
+
+// structMaps caches the result of structMapOf by type.
+var structMaps sync.Map // reflect.Type -> map[string][]int
+
+// structMapOf returns the index paths of the fields of the struct type t,
+// keyed by lower-cased column name.
+func structMapOf(t reflect.Type) map[string][]int {
+	if m, ok := structMaps.Load(t); ok {
+		return m.(map[string][]int)
+	}
+
+	m := make(map[string][]int)
+	addFields(m, t, nil)
+	actual, _ := structMaps.LoadOrStore(t, m)
+	return actual.(map[string][]int)
+}
+
+// addFields adds the fields of t, reached through index, to m. Fields of t
+// shadow those of its embedded structs, which are added after them.
+func addFields(m map[string][]int, t reflect.Type, index []int) {
+	var embedded []reflect.StructField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag, tagged := f.Tag.Lookup("db")
+		if tag == "-" {
+			continue
+		}
+
+		ft := f.Type
+		if ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		if f.Anonymous && !tagged && ft.Kind() == reflect.Struct && !isScalar(ft) {
+			if !f.IsExported() && f.Type.Kind() == reflect.Pointer {
+				// A nil pointer to an unexported type cannot be allocated.
+				continue
+			}
+			embedded = append(embedded, f)
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+
+		name := tag
+		if !tagged {
+			name = f.Name
+		}
+		key := strings.ToLower(name)
+		if _, ok := m[key]; !ok {
+			m[key] = append(append([]int(nil), index...), i)
+		}
+	}
+
+	for _, f := range embedded {
+		ft := f.Type
+		if ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		addFields(m, ft, append(append([]int(nil), index...), f.Index...))
+	}
+}
+
+// fieldByPath returns the field of the struct rv at path, allocating nil
+// pointers to embedded structs on the way.
+func fieldByPath(rv reflect.Value, path []int) reflect.Value {
+	for i, idx := range path {
+		rv = rv.Field(idx)
+		if i < len(path)-1 && rv.Kind() == reflect.Pointer {
+			if rv.IsNil() {
+				rv.Set(reflect.New(rv.Type().Elem()))
+			}
+			rv = rv.Elem()
+		}
+	}
+	return rv
+}
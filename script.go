@@ -0,0 +1,83 @@
+package txnode
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// ExecScript splits script into statements, see SplitStatements, and executes
+// them one after the other in the node's transaction, beginning it on db if
+// needed, so that a schema or seed script applies atomically. It stops at the
+// first failed statement.
+func (txn *TxNode) ExecScript(ctx context.Context, db DB, script string) error {
+	for i, stmt := range SplitStatements(txn.dialect(), script) {
+		if _, err := txn.ExecContext(ctx, db, stmt); err != nil {
+			return fmt.Errorf("txnode: script statement %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// SplitStatements splits script into the statements of dialect d, separated
+// by semicolons and, for SQL Server, by GO lines. Semicolons inside string
+// literals, quoted identifiers, Postgres dollar-quoted strings and comments do
+// not separate statements, and neither do quotes escaped with a backslash in
+// MySQL strings and Postgres E'...' strings. Statements are trimmed of
+// surrounding whitespace, and empty statements, including those made of
+// comments only, are dropped.
+func SplitStatements(d Dialect, script string) []string {
+	var (
+		stmts []string
+		start int
+		q     = d.quoting()
+	)
+	add := func(end int) {
+		stmt := strings.TrimSpace(script[start:end])
+		if sanitizeQuery(stmt, q) != "" {
+			stmts = append(stmts, stmt)
+		}
+	}
+
+	for i := 0; i < len(script); {
+		if end := skipLiteral(q, script, i); end > i {
+			i = end
+			continue
+		}
+
+		switch {
+		case script[i] == ';':
+			add(i)
+			i++
+			start = i
+		case d == DialectSQLServer && (script[i] == 'G' || script[i] == 'g') && isGoLine(script, i):
+			add(i)
+			i = lineEnd(script, i)
+			start = i
+		default:
+			i++
+		}
+	}
+	add(len(script))
+	return stmts
+}
+
+// isGoLine reports whether the line starting at or containing i is a SQL
+// Server GO batch separator and i is its first non-blank character.
+func isGoLine(s string, i int) bool {
+	lineStart := strings.LastIndexByte(s[:i], '\n') + 1
+	if strings.TrimSpace(s[lineStart:i]) != "" {
+		return false
+	}
+	line := strings.TrimSpace(s[i:lineEnd(s, i)])
+	return strings.EqualFold(line, "GO")
+}
+
+// lineEnd returns the index of the newline ending the line containing i, or
+// len(s).
+func lineEnd(s string, i int) int {
+	if end := strings.IndexByte(s[i:], '\n'); end >= 0 {
+		return i + end
+	}
+	return len(s)
+}
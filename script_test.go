@@ -0,0 +1,75 @@
+package txnode_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/MartellOnell/txnode"
+)
+
+func TestSplitStatements(t *testing.T) {
+	tests := []struct {
+		name    string
+		dialect txnode.Dialect
+		script  string
+		want    []string
+	}{
+		{
+			"postgres", txnode.DialectPostgres,
+			"CREATE TABLE t (v text);\nINSERT INTO t VALUES ('a;b');\n",
+			[]string{"CREATE TABLE t (v text)", "INSERT INTO t VALUES ('a;b')"},
+		},
+		{
+			"postgres standard string", txnode.DialectPostgres,
+			`INSERT INTO t VALUES ('C:\'); SELECT 2`,
+			[]string{`INSERT INTO t VALUES ('C:\')`, "SELECT 2"},
+		},
+		{
+			"postgres escape string", txnode.DialectPostgres,
+			`INSERT INTO t VALUES (E'a\'; DROP', 1); SELECT 2`,
+			[]string{`INSERT INTO t VALUES (E'a\'; DROP', 1)`, "SELECT 2"},
+		},
+		{
+			"postgres dollar quoting", txnode.DialectPostgres,
+			"CREATE FUNCTION f() RETURNS int AS $$ SELECT 1; $$ LANGUAGE sql; SELECT f()",
+			[]string{"CREATE FUNCTION f() RETURNS int AS $$ SELECT 1; $$ LANGUAGE sql", "SELECT f()"},
+		},
+		{
+			"postgres comments", txnode.DialectPostgres,
+			"-- first; not split\nSELECT 1; /* ; */ SELECT 2; -- only a comment",
+			[]string{"-- first; not split\nSELECT 1", "/* ; */ SELECT 2"},
+		},
+		{
+			"mysql backslash", txnode.DialectMySQL,
+			`INSERT INTO t VALUES ('a\'; DROP', 1); SELECT 2`,
+			[]string{`INSERT INTO t VALUES ('a\'; DROP', 1)`, "SELECT 2"},
+		},
+		{
+			"mysql double-quoted", txnode.DialectMySQL,
+			`INSERT INTO t VALUES ("a\"; b"); SELECT 2`,
+			[]string{`INSERT INTO t VALUES ("a\"; b")`, "SELECT 2"},
+		},
+		{
+			"mysql hash comment", txnode.DialectMySQL,
+			"SELECT 1; # a; b\nSELECT `x;y` FROM t; # trailing",
+			[]string{"SELECT 1", "# a; b\nSELECT `x;y` FROM t"},
+		},
+		{
+			"sqlite", txnode.DialectSQLite,
+			`CREATE TABLE "a;b" (v); INSERT INTO "a;b" VALUES ('it''s; fine')`,
+			[]string{`CREATE TABLE "a;b" (v)`, `INSERT INTO "a;b" VALUES ('it''s; fine')`},
+		},
+		{
+			"sqlserver go", txnode.DialectSQLServer,
+			"CREATE TABLE t (v int)\nGO\nINSERT INTO t VALUES (1)\n  go  \nSELECT 'GO'",
+			[]string{"CREATE TABLE t (v int)", "INSERT INTO t VALUES (1)", "SELECT 'GO'"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := txnode.SplitStatements(tt.dialect, tt.script); !slices.Equal(got, tt.want) {
+				t.Errorf("SplitStatements(%v, %q) = %q, want %q", tt.dialect, tt.script, got, tt.want)
+			}
+		})
+	}
+}
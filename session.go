@@ -0,0 +1,154 @@
+package txnode
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sessionReset is the statement restoring a session setting that was changed
+// for the transaction, in the dialects where settings outlive it.
+type sessionReset struct {
+	name  string
+	query string
+	args  []any
+}
+
+// hasSessionReset reports whether the statement restoring the session setting
+// name is already recorded, so that it restores the value from before the
+// first change.
+func (txn *TxNode) hasSessionReset(name string) bool {
+	root := txn.root()
+
+	root.mu.Lock()
+	defer root.mu.Unlock()
+	return slices.ContainsFunc(root.sessionResets, func(r sessionReset) bool {
+		return strings.EqualFold(r.name, name)
+	})
+}
+
+// addSessionReset records query, run with args, to restore the session setting
+// name before the transaction ends.
+func (txn *TxNode) addSessionReset(name, query string, args ...any) {
+	root := txn.root()
+
+	root.mu.Lock()
+	defer root.mu.Unlock()
+	root.sessionResets = append(root.sessionResets, sessionReset{name: name, query: query, args: args})
+}
+
+// resetSession restores the session settings changed for the transaction tx,
+// in the reverse order, before the commit or the rollback. If one cannot be
+// restored, the connection is discarded instead of going back to the pool
+// with the transaction's settings, see discardConn.
+func (txn *TxNode) resetSession(tx *sql.Tx) {
+	txn.mu.Lock()
+	resets := txn.sessionResets
+	txn.sessionResets = nil
+	txn.mu.Unlock()
+
+	ctx := txn.hookContext()
+	for _, r := range slices.Backward(resets) {
+		if _, err := tx.ExecContext(ctx, r.query, r.args...); err != nil {
+			txn.log(slog.LevelWarn, "reset session setting",
+				slog.String("setting", r.name), slog.Any("error", err))
+			txn.badConn.Store(true)
+			return
+		}
+	}
+}
+
+// sessionSettings reports whether the dialect changes settings for a
+// transaction in the session, where they outlive it, see SetLocal.
+func (d Dialect) sessionSettings() bool {
+	return d == DialectMySQL || d == DialectSQLServer
+}
+
+// beginTx begins the transaction on beginner. In the dialects where session
+// settings outlive the transaction, it begins it on a connection taken from
+// beginner, if it is a *sql.DB, so that the connection can be discarded when
+// its settings cannot be restored. The node closes the connection once the
+// transaction ends. Other beginners, such as wrappers of a *sql.DB, begin the
+// transaction themselves.
+func (txn *TxNode) beginTx(ctx context.Context, beginner Beginner) (*sql.Tx, error) {
+	pool, ok := beginner.(*sql.DB)
+	if !ok || txn.onConn || !txn.cfg.dialect.sessionSettings() {
+		return beginner.BeginTx(ctx, txn.txOptions())
+	}
+
+	conn, err := pool.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := conn.BeginTx(ctx, txn.txOptions())
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	txn.conn = conn
+	return tx, nil
+}
+
+// discardConn closes conn, telling the pool to drop its driver connection
+// rather than reuse it.
+func discardConn(conn *sql.Conn) {
+	_ = conn.Raw(func(any) error {
+		return driver.ErrBadConn
+	})
+	_ = conn.Close()
+}
+
+// sessionTimeout is the session variable standing for a timeout of
+// WithStatementTimeout or WithLockTimeout in a dialect without
+// transaction-scoped settings.
+type sessionTimeout struct {
+	name string
+	// get reads the current value and set, followed by a value in unit,
+	// changes it.
+	get, set string
+	unit     time.Duration
+}
+
+// sessionTimeouts holds the session variables of the timeouts by dialect.
+var sessionTimeouts = map[Dialect]map[string]sessionTimeout{
+	DialectMySQL: {
+		"statement_timeout": {
+			"max_execution_time",
+			"SELECT @@SESSION.max_execution_time", "SET SESSION max_execution_time = ", time.Millisecond,
+		},
+		"lock_timeout": {
+			"innodb_lock_wait_timeout",
+			"SELECT @@SESSION.innodb_lock_wait_timeout", "SET SESSION innodb_lock_wait_timeout = ", time.Second,
+		},
+	},
+	DialectSQLServer: {
+		"lock_timeout": {"LOCK_TIMEOUT", "SELECT @@LOCK_TIMEOUT", "SET LOCK_TIMEOUT ", time.Millisecond},
+	},
+}
+
+// setSessionTimeout sets the timeout name to d with the session variable of
+// the node's dialect, and records how to restore it.
+func (txn *TxNode) setSessionTimeout(ctx context.Context, tx *sql.Tx, name string, d time.Duration) error {
+	s, ok := sessionTimeouts[txn.cfg.dialect][name]
+	if !ok {
+		return ErrUnsupported
+	}
+
+	var prior int64
+	if err := tx.QueryRowContext(ctx, s.get).Scan(&prior); err != nil {
+		return err
+	}
+	v := max(int64((d+s.unit-1)/s.unit), 1)
+	if _, err := tx.ExecContext(ctx, s.set+strconv.FormatInt(v, 10)); err != nil {
+		return err
+	}
+	if !txn.hasSessionReset(s.name) {
+		txn.addSessionReset(s.name, s.set+strconv.FormatInt(prior, 10))
+	}
+	return nil
+}
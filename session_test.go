@@ -0,0 +1,89 @@
+package txnode_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+
+	"github.com/MartellOnell/txnode"
+)
+
+func TestSetLocalMySQLResetsSession(t *testing.T) {
+	tests := []struct {
+		name     string
+		resetErr error
+		// open is the number of connections left in the pool.
+		open int
+	}{
+		{"restored", nil, 1},
+		{"restore fails", errors.New("connection lost"), 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+			mock.ExpectBegin()
+			mock.ExpectExec("SET @txnode_sql_mode = @@SESSION.sql_mode").WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec("SET SESSION sql_mode = ?").WithArgs("ANSI").WillReturnResult(sqlmock.NewResult(0, 0))
+			reset := mock.ExpectExec("SET SESSION sql_mode = @txnode_sql_mode")
+			if tt.resetErr != nil {
+				reset.WillReturnError(tt.resetErr)
+			} else {
+				reset.WillReturnResult(sqlmock.NewResult(0, 0))
+			}
+			mock.ExpectCommit()
+
+			txn := txnode.New(txnode.WithDialect(txnode.DialectMySQL))
+			txn.SetEnd()
+			if err := txn.SetLocal(context.Background(), db, "sql_mode", "ANSI"); err != nil {
+				t.Fatal(err)
+			}
+			if err := txn.CommitIfNeeded(); err != nil {
+				t.Fatal(err)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Fatal(err)
+			}
+			if got := db.Stats().OpenConnections; got != tt.open {
+				t.Errorf("%d open connections, want %d", got, tt.open)
+			}
+		})
+	}
+}
+
+func TestSetLocalMySQLResetsSessionOnAbort(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	mock.ExpectBegin()
+	mock.ExpectExec("SET @txnode_sql_mode = @@SESSION.sql_mode").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("SET SESSION sql_mode = ?").WithArgs("ANSI").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("SET SESSION sql_mode = @txnode_sql_mode").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	txn := txnode.New(txnode.WithDialect(txnode.DialectMySQL), txnode.WithMaxDuration(10*time.Millisecond))
+	txn.SetEnd()
+	if err := txn.SetLocal(context.Background(), db, "sql_mode", "ANSI"); err != nil {
+		t.Fatal(err)
+	}
+	for txn.Err() == nil {
+		time.Sleep(time.Millisecond)
+	}
+	if err := txn.CommitIfNeeded(); !errors.Is(err, txnode.ErrTxTimeout) {
+		t.Fatalf("CommitIfNeeded() = %v, want ErrTxTimeout", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+	if got := db.Stats().OpenConnections; got != 1 {
+		t.Errorf("%d open connections, want 1", got)
+	}
+}
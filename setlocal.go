@@ -0,0 +1,96 @@
+package txnode
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+)
+
+// SetLocal sets the run-time parameter name to value for the rest of the
+// node's transaction, beginning it on db if needed, as SET LOCAL does in
+// Postgres. The previous value is restored when the transaction ends, so
+// settings such as role, timeouts or the custom parameters read by row level
+// security policies never leak to other users of the connection:
+//
+//	txn.SetLocal(ctx, db, "app.tenant_id", tenant)
+//
+// SetLocal uses set_config, so value is bound as an argument rather than
+// interpolated into the statement.
+//
+// MySQL and SQL Server have no transaction-scoped settings, so the session's
+// is changed instead and restored right before the commit or the rollback.
+// If a setting cannot be restored, for example because the transaction's
+// context is done, the connection is discarded instead of going back to the
+// pool.
+// In MySQL name is a system variable set with SET SESSION, value being bound
+// as an integer if it is one. In SQL Server name is a key of the session
+// context set with sp_set_session_context, which row level security policies
+// read with SESSION_CONTEXT. SQLite fails with ErrUnsupported, and a nil txn
+// fails with ErrNotStarted.
+func (txn *TxNode) SetLocal(ctx context.Context, db DB, name, value string) error {
+	if txn == nil {
+		return fmt.Errorf("txnode: set local %s: %w", name, ErrNotStarted)
+	}
+
+	var err error
+	switch txn.cfg.dialect {
+	case DialectPostgres:
+		var current string
+		err = txn.QueryRowContext(ctx, db, "SELECT set_config($1, $2, true)", name, value).Scan(&current)
+	case DialectMySQL:
+		err = txn.setMySQLSession(ctx, db, name, value)
+	case DialectSQLServer:
+		err = txn.setSessionContext(ctx, db, name, value)
+	default:
+		err = ErrUnsupported
+	}
+	if err != nil {
+		return fmt.Errorf("txnode: set local %s: %w", name, err)
+	}
+	return nil
+}
+
+// setMySQLSession sets the MySQL system variable name to value for the
+// session, saving its value in a user variable first to restore it.
+func (txn *TxNode) setMySQLSession(ctx context.Context, db DB, name, value string) error {
+	if !isIdentifier(name) {
+		return fmt.Errorf("invalid variable %q", name)
+	}
+
+	if !txn.hasSessionReset(name) {
+		saved := "@txnode_" + name
+		if _, err := txn.ExecContext(ctx, db, "SET "+saved+" = @@SESSION."+name); err != nil {
+			return err
+		}
+		txn.addSessionReset(name, "SET SESSION "+name+" = "+saved)
+	}
+
+	var arg any = value
+	if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+		arg = n
+	}
+	_, err := txn.ExecContext(ctx, db, "SET SESSION "+name+" = ?", arg)
+	return err
+}
+
+// setSessionContext sets the key name of the SQL Server session context to
+// value, reading its value first to restore it.
+func (txn *TxNode) setSessionContext(ctx context.Context, db DB, name, value string) error {
+	set := "EXEC sp_set_session_context @p1, @p2"
+	if key := "session_context:" + name; !txn.hasSessionReset(key) {
+		var prior sql.NullString
+		err := txn.QueryRowContext(ctx, db, "SELECT CAST(SESSION_CONTEXT(@p1) AS nvarchar(4000))", name).Scan(&prior)
+		if err != nil {
+			return err
+		}
+		var restore any
+		if prior.Valid {
+			restore = prior.String
+		}
+		txn.addSessionReset(key, set, name, restore)
+	}
+
+	_, err := txn.ExecContext(ctx, db, set, name, value)
+	return err
+}
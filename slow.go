@@ -0,0 +1,48 @@
+package txnode
+
+import (
+	"hash/fnv"
+	"log/slog"
+	"strconv"
+	"time"
+)
+
+// WithSlowQueryThreshold logs every statement prepared or executed through the
+// node that takes at least d, at warning level with its duration and the
+// fingerprint of its query, see Fingerprint. Such statements are also flagged
+// as slow in the StmtEvent passed to the observers. A d of zero disables the
+// detection.
+func WithSlowQueryThreshold(d time.Duration) Option {
+	return func(c *config) {
+		c.slowQuery = d
+	}
+}
+
+// Fingerprint returns a short hash identifying the shape of query: queries
+// that differ only in their literals or whitespace have the same fingerprint.
+func Fingerprint(query string) string {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(SanitizeQuery(query)))
+	return strconv.FormatUint(h.Sum64(), 16)
+}
+
+// isSlow reports whether a statement that took d exceeds the threshold set
+// with WithSlowQueryThreshold.
+func (txn *TxNode) isSlow(d time.Duration) bool {
+	return txn.cfg.slowQuery > 0 && d >= txn.cfg.slowQuery
+}
+
+// logSlowStmt logs a statement that exceeded the slow query threshold.
+func (txn *TxNode) logSlowStmt(kind stmtKind, query string, d time.Duration, plan string) {
+	attrs := []slog.Attr{
+		slog.String("kind", kind.String()),
+		slog.String("query", txn.sanitize(query)),
+		slog.String("fingerprint", Fingerprint(query)),
+		slog.Duration("stmt_duration", d),
+		slog.Duration("threshold", txn.cfg.slowQuery),
+	}
+	if plan != "" {
+		attrs = append(attrs, slog.String("plan", plan))
+	}
+	txn.log(slog.LevelWarn, "slow statement", attrs...)
+}
@@ -0,0 +1,91 @@
+package txnode
+
+import (
+	"reflect"
+	"strings"
+	"time"
+)
+
+// SQLite primary result codes.
+const (
+	sqliteBusy       = 5
+	sqliteLocked     = 6
+	sqliteConstraint = 19
+)
+
+// maxBusyBackoff caps the pause between two attempts made for SQLITE_BUSY.
+const maxBusyBackoff = time.Second
+
+// WithSQLiteBusyRetry enables retrying on SQLITE_BUSY and SQLITE_LOCKED errors.
+// Beginning the transaction and every statement run through the node are
+// retried up to attempts times in total, pausing for backoff and doubling the
+// pause after each attempt. Run also retries the whole transaction when it
+// fails with one of these errors, for example on commit.
+func WithSQLiteBusyRetry(attempts int, backoff time.Duration) Option {
+	return func(c *config) {
+		c.busyRetry = RetryPolicy{
+			MaxAttempts: attempts,
+			Backoff:     backoff,
+			MaxBackoff:  maxBusyBackoff,
+			Multiplier:  2,
+		}
+	}
+}
+
+// IsBusy reports whether err is an SQLite SQLITE_BUSY or SQLITE_LOCKED error,
+// which means another connection holds a conflicting lock on the database.
+// It understands mattn/go-sqlite3 and modernc.org/sqlite errors without
+// importing either driver.
+func IsBusy(err error) bool {
+	return walkErrors(err, func(e error) bool {
+		code, ok := sqliteErrorCode(e)
+		if !ok {
+			msg := e.Error()
+			return strings.Contains(msg, "database is locked") ||
+				strings.Contains(msg, "database table is locked") ||
+				strings.Contains(msg, "SQLITE_BUSY")
+		}
+
+		code &= 0xff // strip the extended result code
+		return code == sqliteBusy || code == sqliteLocked
+	})
+}
+
+// sqliteErrorCode returns the result code carried by an SQLite driver error.
+func sqliteErrorCode(err error) (int, bool) {
+	v := reflect.ValueOf(err)
+	if !strings.Contains(v.Type().PkgPath()+indirectPkgPath(v), "sqlite") {
+		return 0, false
+	}
+
+	if e, ok := err.(interface{ Code() int }); ok {
+		return e.Code(), true
+	}
+
+	if v.Kind() == reflect.Pointer {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return 0, false
+	}
+
+	f := v.FieldByName("Code")
+	if !f.IsValid() || !f.CanInt() {
+		return 0, false
+	}
+	return int(f.Int()), true
+}
+
+// indirectPkgPath returns the package path of the type v points to, if any.
+func indirectPkgPath(v reflect.Value) string {
+	if v.Kind() != reflect.Pointer {
+		return ""
+	}
+	return v.Type().Elem().PkgPath()
+}
+
+// isBusyRetryable reports whether err may be retried under the busy retry policy.
+func (txn *TxNode) isBusyRetryable(attempt int, err error) bool {
+	return attempt < txn.cfg.busyRetry.MaxAttempts &&
+		txn.cfg.classifier.Classify(err) == CategoryBusy
+}
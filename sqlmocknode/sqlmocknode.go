@@ -0,0 +1,115 @@
+// Package sqlmocknode sets up DATA-DOG/go-sqlmock expectations matching what a
+// txnode chain does on its database, so that sqlmock-based test suites can
+// cover code using txnode without ordering expectations by hand.
+//
+//	db, mock, _ := sqlmock.New()
+//	sqlmocknode.ExpectCommitted(mock, func(e *sqlmocknode.Expectations) {
+//		e.Exec("UPDATE users SET name = $1").WithArgs("alice").
+//			WillReturnResult(sqlmock.NewResult(0, 1))
+//	})
+//
+// Queries are given as the text passed to txnode and quoted for sqlmock's
+// default regular expression matcher.
+package sqlmocknode
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// Expectations registers the expectations for the statements of one
+// transaction, in the order the chain runs them.
+type Expectations struct {
+	mock sqlmock.Sqlmock
+}
+
+// New returns the Expectations registering on mock.
+func New(mock sqlmock.Sqlmock) *Expectations {
+	return &Expectations{mock: mock}
+}
+
+// ExpectCommitted expects a transaction that begins, runs the statements
+// registered by fn and commits.
+func ExpectCommitted(mock sqlmock.Sqlmock, fn func(e *Expectations)) {
+	e := New(mock)
+	e.Begin()
+	fn(e)
+	e.Commit()
+}
+
+// ExpectRolledBack expects a transaction that begins, runs the statements
+// registered by fn and rolls back.
+func ExpectRolledBack(mock sqlmock.Sqlmock, fn func(e *Expectations)) {
+	e := New(mock)
+	e.Begin()
+	fn(e)
+	e.Rollback()
+}
+
+// Begin expects the chain to begin its transaction, which it does lazily on
+// the first statement or explicitly with Begin.
+func (e *Expectations) Begin() *sqlmock.ExpectedBegin {
+	return e.mock.ExpectBegin()
+}
+
+// Commit expects CommitIfNeeded to commit the transaction.
+func (e *Expectations) Commit() *sqlmock.ExpectedCommit {
+	return e.mock.ExpectCommit()
+}
+
+// Rollback expects the transaction to be rolled back.
+func (e *Expectations) Rollback() *sqlmock.ExpectedRollback {
+	return e.mock.ExpectRollback()
+}
+
+// Prepare expects PrepareQuery with query. The statement is expected to be
+// closed, which the node does once the transaction ends unless statement
+// tracking was disabled with txnode.WithStmtTracking.
+func (e *Expectations) Prepare(query string) *sqlmock.ExpectedPrepare {
+	return e.mock.ExpectPrepare(quote(query)).WillBeClosed()
+}
+
+// Exec expects ExecContext with query.
+func (e *Expectations) Exec(query string) *sqlmock.ExpectedExec {
+	return e.mock.ExpectExec(quote(query))
+}
+
+// Query expects QueryContext or QueryRowContext with query.
+func (e *Expectations) Query(query string) *sqlmock.ExpectedQuery {
+	return e.mock.ExpectQuery(quote(query))
+}
+
+// Savepoint expects the savepoint name to be created, as Propagate does for a
+// Nested child, see Nested.
+func (e *Expectations) Savepoint(name string) {
+	e.exec("SAVEPOINT " + name)
+}
+
+// ReleaseSavepoint expects the savepoint name to be released, as committing a
+// Nested child does.
+func (e *Expectations) ReleaseSavepoint(name string) {
+	e.exec("RELEASE SAVEPOINT " + name)
+}
+
+// RollbackToSavepoint expects a rollback to the savepoint name, as rolling
+// back a Nested child does.
+func (e *Expectations) RollbackToSavepoint(name string) {
+	e.exec("ROLLBACK TO SAVEPOINT " + name)
+}
+
+// Nested returns the name of the savepoint of the n-th Nested child of a
+// transaction, counting from 1.
+func Nested(n int) string {
+	return fmt.Sprintf("txnode_sp_%d", n)
+}
+
+func (e *Expectations) exec(query string) {
+	e.mock.ExpectExec("^" + quote(query) + "$").WillReturnResult(sqlmock.NewResult(0, 0))
+}
+
+// quote escapes query for sqlmock's regular expression matcher.
+func quote(query string) string {
+	return regexp.QuoteMeta(query)
+}
@@ -0,0 +1,272 @@
+// Package sqlxnode adapts txnode to jmoiron/sqlx, so chained transactions can
+// use sqlx statements, struct scanning and named queries. The helpers run
+// their statements through the underlying txnode.TxNode, so the options of the
+// node, such as txnode.WithPolicy or txnode.WithHistory, apply to them as well.
+package sqlxnode
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"reflect"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/MartellOnell/txnode"
+)
+
+// TxNode represents a node in a transaction chain backed by sqlx.
+// The transaction lifecycle is managed by an underlying txnode.TxNode.
+type TxNode struct {
+	node *txnode.TxNode
+	tx   *sqlx.Tx
+	// begun is the transaction begun through sqlx for the node, until the
+	// node has set it up, see adopt.
+	begun *sqlx.Tx
+}
+
+// New creates a new TxNode ready to start a transaction.
+func New(opts ...txnode.Option) *TxNode {
+	return &TxNode{
+		node: txnode.New(opts...),
+	}
+}
+
+// Node returns the underlying txnode.TxNode.
+func (txn *TxNode) Node() *txnode.TxNode {
+	if txn == nil {
+		return nil
+	}
+	return txn.node
+}
+
+// Tx returns the underlying transaction and whether one has been started.
+func (txn *TxNode) Tx() (*sqlx.Tx, bool) {
+	if txn == nil || txn.tx == nil {
+		return nil, false
+	}
+	return txn.tx, true
+}
+
+// UnsetEnd marks this node as not being the end of the transaction chain.
+func (txn *TxNode) UnsetEnd() {
+	txn.node.UnsetEnd()
+}
+
+// SetEnd marks this node as the end of the transaction chain.
+func (txn *TxNode) SetEnd() {
+	txn.node.SetEnd()
+}
+
+// PrepareQuery prepares a SQL statement. It begins a transaction on first call
+// or reuses the existing transaction. If txn is nil the statement is prepared on db.
+func (txn *TxNode) PrepareQuery(
+	ctx context.Context,
+	db *sqlx.DB,
+	query string,
+) (*sqlx.Stmt, error) {
+	if txn == nil {
+		return db.PreparexContext(ctx, query)
+	}
+
+	defer txn.adopt()
+	stmt, err := txn.node.PrepareQuery(ctx, txn.beginner(db), query)
+	if err != nil {
+		return nil, err
+	}
+
+	return &sqlx.Stmt{Stmt: stmt, Mapper: db.Mapper}, nil
+}
+
+// ExecContext executes a query without returning rows inside the chain's transaction.
+func (txn *TxNode) ExecContext(
+	ctx context.Context,
+	db *sqlx.DB,
+	query string,
+	args ...any,
+) (sql.Result, error) {
+	if txn == nil {
+		return db.ExecContext(ctx, query, args...)
+	}
+	defer txn.adopt()
+	return txn.node.ExecContext(ctx, txn.beginner(db), query, args...)
+}
+
+// Get scans a single row into dest inside the chain's transaction.
+func (txn *TxNode) Get(
+	ctx context.Context,
+	db *sqlx.DB,
+	dest any,
+	query string,
+	args ...any,
+) error {
+	if txn == nil {
+		return db.GetContext(ctx, dest, query, args...)
+	}
+
+	defer txn.adopt()
+	rows, err := txn.node.QueryContext(ctx, txn.beginner(db), query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	r := &sqlx.Rows{Rows: rows, Mapper: db.Mapper}
+	if !r.Next() {
+		if err := r.Err(); err != nil {
+			return err
+		}
+		return sql.ErrNoRows
+	}
+	if t := reflect.TypeOf(dest); t == nil || t.Kind() != reflect.Pointer || scannable(db, t.Elem()) {
+		err = r.Scan(dest)
+	} else {
+		err = r.StructScan(dest)
+	}
+	if err != nil {
+		return err
+	}
+	return r.Close()
+}
+
+// Select scans all rows into the slice pointed to by dest inside the chain's transaction.
+func (txn *TxNode) Select(
+	ctx context.Context,
+	db *sqlx.DB,
+	dest any,
+	query string,
+	args ...any,
+) error {
+	if txn == nil {
+		return db.SelectContext(ctx, dest, query, args...)
+	}
+
+	defer txn.adopt()
+	rows, err := txn.node.QueryContext(ctx, txn.beginner(db), query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	return scanAll(db, &sqlx.Rows{Rows: rows, Mapper: db.Mapper}, dest)
+}
+
+// NamedExec executes a query with named parameters bound from arg, a struct
+// or a map, inside the chain's transaction.
+func (txn *TxNode) NamedExec(
+	ctx context.Context,
+	db *sqlx.DB,
+	query string,
+	arg any,
+) (sql.Result, error) {
+	if txn == nil {
+		return db.NamedExecContext(ctx, query, arg)
+	}
+
+	query, args, err := db.BindNamed(query, arg)
+	if err != nil {
+		return nil, err
+	}
+	defer txn.adopt()
+	return txn.node.ExecContext(ctx, txn.beginner(db), query, args...)
+}
+
+// RollbackTransaction rolls back the transaction if one exists.
+func (txn *TxNode) RollbackTransaction() error {
+	return txn.Node().RollbackTransaction()
+}
+
+// CommitIfNeeded commits the transaction only if this node is marked as the end.
+func (txn *TxNode) CommitIfNeeded() error {
+	return txn.Node().CommitIfNeeded()
+}
+
+// RollbackTransactionAndLog rolls back the transaction and logs both the rollback
+// and the original error. Returns a wrapped error with the operation name,
+// joined with the rollback error if the rollback failed as well.
+func (txn *TxNode) RollbackTransactionAndLog(
+	log *slog.Logger,
+	op string,
+	err error,
+) error {
+	return txn.Node().RollbackTransactionAndLog(log, op, err)
+}
+
+// scanAll scans every row into the slice pointed to by dest, like the Select
+// of sqlx.
+func scanAll(db *sqlx.DB, rows *sqlx.Rows, dest any) error {
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Slice {
+		return sqlx.StructScan(rows, dest)
+	}
+	slice := v.Elem()
+	elem := slice.Type().Elem()
+	base := elem
+	if base.Kind() == reflect.Pointer {
+		base = base.Elem()
+	}
+	if !scannable(db, base) {
+		return sqlx.StructScan(rows, dest)
+	}
+
+	for rows.Next() {
+		p := reflect.New(base)
+		if err := rows.Scan(p.Interface()); err != nil {
+			return err
+		}
+		if elem.Kind() == reflect.Pointer {
+			slice.Set(reflect.Append(slice, p))
+		} else {
+			slice.Set(reflect.Append(slice, p.Elem()))
+		}
+	}
+	return rows.Err()
+}
+
+// scannable reports whether values of type t are scanned as a single column
+// rather than field by field, following the rules of sqlx.
+func scannable(db *sqlx.DB, t reflect.Type) bool {
+	if reflect.PointerTo(t).Implements(scannerType) || t.Kind() != reflect.Struct {
+		return true
+	}
+	return len(db.Mapper.TypeMap(t).Index) == 0
+}
+
+var scannerType = reflect.TypeFor[sql.Scanner]()
+
+func (txn *TxNode) beginner(db *sqlx.DB) beginner {
+	return beginner{DB: db, txn: txn}
+}
+
+// beginner lets the underlying node begin its transaction through sqlx, so
+// that the node and the sqlx helpers share the same *sqlx.Tx.
+type beginner struct {
+	*sqlx.DB
+	txn *TxNode
+}
+
+func (b beginner) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	tx, err := b.DB.BeginTxx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	b.txn.begun = tx
+	return tx.Tx, nil
+}
+
+// adopt makes the transaction begun through sqlx the chain's once the node
+// has set it up, and rolls it back if the node failed to.
+func (txn *TxNode) adopt() {
+	begun := txn.begun
+	if begun == nil {
+		return
+	}
+	txn.begun = nil
+
+	if tx, ok := txn.node.Tx(); ok && tx == begun.Tx {
+		txn.tx = begun
+		return
+	}
+	_ = begun.Rollback()
+}
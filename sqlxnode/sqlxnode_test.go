@@ -0,0 +1,69 @@
+package sqlxnode_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/MartellOnell/txnode"
+	"github.com/MartellOnell/txnode/sqlxnode"
+)
+
+func TestBeginSetsTxOnlyOnceSetUp(t *testing.T) {
+	tests := []struct {
+		name    string
+		initErr error
+	}{
+		{"set up", nil},
+		{"setup fails", errors.New("cannot set timeout")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+			if err != nil {
+				t.Fatal(err)
+			}
+			db := sqlx.NewDb(conn, "postgres")
+			mock.ExpectBegin()
+			timeout := mock.ExpectExec("SET LOCAL statement_timeout = 1000")
+			if tt.initErr != nil {
+				timeout.WillReturnError(tt.initErr)
+				mock.ExpectRollback()
+			} else {
+				timeout.WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec("UPDATE t SET v = 1").WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			}
+
+			txn := sqlxnode.New(txnode.WithStatementTimeout(time.Second))
+			txn.SetEnd()
+			_, err = txn.ExecContext(context.Background(), db, "UPDATE t SET v = 1")
+			_, ok := txn.Tx()
+			if tt.initErr != nil {
+				if !errors.Is(err, tt.initErr) {
+					t.Fatalf("ExecContext() = %v, want %v", err, tt.initErr)
+				}
+				if ok {
+					t.Error("Tx() reports the transaction whose setup failed")
+				}
+			} else {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !ok {
+					t.Error("Tx() reports no transaction")
+				}
+				if err := txn.CommitIfNeeded(); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package txnode
+
+import (
+	"database/sql"
+	"runtime"
+)
+
+// maxEndFrames bounds the call stack recorded where a transaction ended, which
+// only needs to reach out of this package.
+const maxEndFrames = 16
+
+// State is the lifecycle state of a node's transaction.
+type State int
+
+const (
+	// StateIdle is the state of a node that has not begun its transaction.
+	StateIdle State = iota
+	// StateActive is the state of a node whose transaction is open.
+	StateActive
+	// StateCommitted is the state of a node whose transaction has been
+	// committed, or whose savepoint has been released for a Nested child.
+	StateCommitted
+	// StateRolledBack is the state of a node whose transaction has been
+	// rolled back, including after a failed commit or an abort.
+	StateRolledBack
+	// StatePrepared is the state of a node whose transaction has been
+	// prepared for a two-phase commit, see Coordinator. The outcome is decided
+	// on the database, outside of the node.
+	StatePrepared
+	// StateHandedOff is the state of a wrapped node created with
+	// WithExternalOwnership after CommitIfNeeded: the node is done with the
+	// transaction and its owner decides the outcome.
+	StateHandedOff
+)
+
+func (s State) String() string {
+	switch s {
+	case StateIdle:
+		return "idle"
+	case StateActive:
+		return "active"
+	case StateCommitted:
+		return "committed"
+	case StateRolledBack:
+		return "rolled back"
+	case StatePrepared:
+		return "prepared"
+	case StateHandedOff:
+		return "handed off"
+	default:
+		return "unknown"
+	}
+}
+
+// State returns the state of the node's transaction. A node that is not the
+// end of its chain stays active after CommitIfNeeded, which lets callers tell
+// whether a commit actually took place. A nil node is always idle.
+func (txn *TxNode) State() State {
+	if txn == nil {
+		return StateIdle
+	}
+
+	txn.mu.Lock()
+	defer txn.mu.Unlock()
+	return txn.state
+}
+
+// IsActive reports whether the node's transaction is open.
+func (txn *TxNode) IsActive() bool {
+	return txn.State() == StateActive
+}
+
+// IsDone reports whether the node's transaction has been committed or rolled
+// back.
+func (txn *TxNode) IsDone() bool {
+	s := txn.State()
+	return s == StateCommitted || s == StateRolledBack
+}
+
+// setState records the state of the node.
+func (txn *TxNode) setState(s State) {
+	txn.mu.Lock()
+	defer txn.mu.Unlock()
+	txn.state = s
+	txn.noteEnd()
+}
+
+// noteEnd records the callers that ended the transaction if its state is
+// final, for TxClosedError. Only the program counters are recorded; they are
+// resolved to a function and line once an error reports them. It must be
+// called with mu held.
+func (txn *TxNode) noteEnd() {
+	switch txn.state {
+	case StateCommitted, StateRolledBack, StatePrepared, StateHandedOff:
+		if txn.endedDepth == 0 {
+			txn.endedDepth = runtime.Callers(2, txn.endedBy[:])
+		}
+	}
+}
+
+// closedErr returns a *TxClosedError if the transaction of the node, or of a
+// node it is nested in, has ended.
+func (txn *TxNode) closedErr() error {
+	for n := txn; n != nil; n = n.parent {
+		n.mu.Lock()
+		state, pcs := n.state, n.endedBy[:n.endedDepth]
+		n.mu.Unlock()
+		switch state {
+		case StateCommitted, StateRolledBack, StatePrepared, StateHandedOff:
+			return &TxClosedError{ID: n.ID(), State: state, By: firstOutside(pcs)}
+		}
+	}
+	return nil
+}
+
+// WithStrictCompletion makes committing or rolling back a node whose
+// transaction has already ended fail with an error wrapping sql.ErrTxDone,
+// such as ErrAlreadyCommitted, instead of doing nothing.
+func WithStrictCompletion() Option {
+	return func(c *config) {
+		c.strictCompletion = true
+	}
+}
+
+// finished reports whether the node's transaction has ended, and if so the
+// error to return from a further commit or, if commit is false, rollback.
+func (txn *TxNode) finished(commit bool) (bool, error) {
+	switch txn.State() {
+	case StateCommitted:
+		if txn.cfg.strictCompletion {
+			return true, ErrAlreadyCommitted
+		}
+		return true, nil
+	case StateRolledBack:
+		if commit || txn.cfg.strictCompletion {
+			return true, sql.ErrTxDone
+		}
+		return true, nil
+	case StatePrepared:
+		if commit || txn.cfg.strictCompletion {
+			return true, ErrPrepared
+		}
+		return true, nil
+	case StateHandedOff:
+		if txn.cfg.strictCompletion {
+			return true, sql.ErrTxDone
+		}
+		return true, nil
+	default:
+		return false, nil
+	}
+}
@@ -0,0 +1,89 @@
+package txnode
+
+import "time"
+
+// Stats summarizes the work done in a node's transaction.
+type Stats struct {
+	// Duration is the time from begin to commit or rollback, or the age of
+	// the transaction if it is still open.
+	Duration time.Duration
+	// Statements is the number of statements prepared or run through the node.
+	Statements int
+	// RowsAffected is the total number of rows affected by executed statements,
+	// as far as the driver reports them.
+	RowsAffected int64
+	// Retries is the number of times a statement, the begin or, under Run, the
+	// whole transaction was retried.
+	Retries int
+}
+
+// Stats returns the statistics of the node's transaction. Nodes sharing a
+// transaction, such as Nested children, report the statistics of the whole
+// transaction.
+func (txn *TxNode) Stats() Stats {
+	if txn == nil {
+		return Stats{}
+	}
+
+	root := txn.root()
+	root.mu.Lock()
+	defer root.mu.Unlock()
+
+	st := root.stats
+	switch {
+	case root.began.IsZero():
+	case root.ended.IsZero():
+		st.Duration = time.Since(root.began)
+	default:
+		st.Duration = root.ended.Sub(root.began)
+	}
+	return st
+}
+
+// countStmt adds a statement that affected rows rows to the statistics.
+func (txn *TxNode) countStmt(rows int64) {
+	root := txn.root()
+	root.mu.Lock()
+	defer root.mu.Unlock()
+
+	root.stats.Statements++
+	if rows > 0 {
+		root.stats.RowsAffected += rows
+	}
+}
+
+// countRetry adds a retry to the statistics.
+func (txn *TxNode) countRetry() {
+	root := txn.root()
+	root.mu.Lock()
+	defer root.mu.Unlock()
+
+	root.stats.Retries++
+}
+
+// markEnded records the time the transaction ended and its final state, once.
+// It reports false if the end was already recorded. A commit clears the
+// reason of an abort that came too late to roll the transaction back.
+func (txn *TxNode) markEnded(committed bool) bool {
+	txn.mu.Lock()
+	defer txn.mu.Unlock()
+
+	if !txn.ended.IsZero() || txn.began.IsZero() {
+		return false
+	}
+	txn.ended = time.Now()
+	txn.endLeak()
+	// A prepared transaction keeps its state; its outcome is decided by
+	// CommitPrepared or RollbackPrepared.
+	if txn.state != StatePrepared {
+		txn.state = StateRolledBack
+		if committed {
+			txn.state = StateCommitted
+		}
+	}
+	if committed {
+		txn.aborted = nil
+	}
+	txn.noteEnd()
+	return true
+}
@@ -0,0 +1,45 @@
+package txnode
+
+import (
+	"context"
+	"fmt"
+)
+
+// Step is one unit of work of a transaction run by Execute. A Step that also
+// has a Name() string method is named after it in errors; others are named
+// by their position, such as "step 2".
+type Step interface {
+	Run(ctx context.Context, txn *TxNode) error
+}
+
+// StepFunc adapts a function to the Step interface.
+type StepFunc func(ctx context.Context, txn *TxNode) error
+
+// Run calls f.
+func (f StepFunc) Run(ctx context.Context, txn *TxNode) error {
+	return f(ctx, txn)
+}
+
+// Execute begins one transaction on db, runs steps in order and commits after
+// the last one. It stops at the first failing step and rolls the transaction
+// back, returning the step's error prefixed with its name. Execute is a Chain
+// whose last step is the end operation.
+func Execute(ctx context.Context, db DB, steps ...Step) error {
+	c := NewChain()
+	for i, s := range steps {
+		if i == len(steps)-1 {
+			c.End(stepName(i, s), s.Run)
+		} else {
+			c.Then(stepName(i, s), s.Run)
+		}
+	}
+	return c.Run(ctx, db)
+}
+
+// stepName returns the name of the step at index i.
+func stepName(i int, s Step) string {
+	if n, ok := s.(interface{ Name() string }); ok {
+		return n.Name()
+	}
+	return fmt.Sprintf("step %d", i+1)
+}
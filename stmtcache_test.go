@@ -0,0 +1,56 @@
+package txnode_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+
+	"github.com/MartellOnell/txnode"
+)
+
+func TestPrepareQueryStmtCache(t *testing.T) {
+	for _, cache := range []bool{false, true} {
+		db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		if err != nil {
+			t.Fatal(err)
+		}
+		mock.ExpectBegin()
+		mock.ExpectPrepare("INSERT INTO t (v) VALUES ($1)")
+		if !cache {
+			mock.ExpectPrepare("INSERT INTO t (v) VALUES ($1)")
+		}
+		mock.ExpectCommit()
+
+		var opts []txnode.Option
+		if cache {
+			opts = append(opts, txnode.WithStmtCache(true))
+		}
+		txn := txnode.New(opts...)
+		txn.SetEnd()
+		ctx := context.Background()
+		first, err := txn.PrepareQuery(ctx, db, "INSERT INTO t (v) VALUES ($1)")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !cache {
+			// Without the cache the statement is the caller's to close.
+			if err := first.Close(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		second, err := txn.PrepareQuery(ctx, db, "INSERT INTO t (v) VALUES ($1)")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := first == second; got != cache {
+			t.Errorf("cache %v: same statement %v, want %v", cache, got, cache)
+		}
+		if err := txn.CommitIfNeeded(); err != nil {
+			t.Fatal(err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Fatalf("cache %v: %v", cache, err)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package txnode
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+)
+
+// openStmt is a statement prepared through the node with its query.
+type openStmt struct {
+	stmt  *sql.Stmt
+	query string
+	// cached reports whether the statement is held by the statement cache,
+	// and reused whether the cache handed it out again.
+	cached, reused bool
+}
+
+// WithStmtLeakWarnings logs a warning with the fingerprint of every statement
+// prepared through the node that the caller has not closed by the time the
+// transaction is committed or rolled back. With statement tracking enabled,
+// see WithStmtTracking, such statements are still closed by the node; the
+// warnings tell which callers rely on it, before tracking is turned off.
+// Statements shared through WithStmtCache are closed by the node rather than
+// by callers, so they are reported only if they were prepared but never used
+// again, taking a cache entry for nothing. Warnings go to the logger set with
+// WithLogger.
+func WithStmtLeakWarnings() Option {
+	return func(c *config) {
+		c.stmtLeakWarn = true
+	}
+}
+
+// watchStmt records a prepared statement for WithStmtLeakWarnings.
+func (txn *TxNode) watchStmt(stmt *sql.Stmt, query string) {
+	if !txn.cfg.stmtLeakWarn {
+		return
+	}
+	root := txn.root()
+	root.prepared = append(root.prepared, openStmt{stmt: stmt, query: query, cached: txn.cfg.stmtCache})
+}
+
+// reuseStmt records that the statement cache handed stmt out again.
+func (txn *TxNode) reuseStmt(stmt *sql.Stmt) {
+	for i := range txn.prepared {
+		if txn.prepared[i].stmt == stmt {
+			txn.prepared[i].reused = true
+		}
+	}
+}
+
+// checkStmtLeaks warns about the statements still open as the transaction
+// ends, which database/sql is about to close with it, and about the cached
+// statements that were never used again.
+func (txn *TxNode) checkStmtLeaks() {
+	for _, s := range txn.prepared {
+		msg := "prepared statement not closed"
+		switch {
+		case s.cached && s.reused:
+			continue
+		case s.cached:
+			msg = "cached statement never used again"
+		case stmtClosed(s.stmt):
+			continue
+		}
+		txn.log(slog.LevelWarn, msg,
+			slog.String("query", txn.sanitize(s.query)),
+			slog.String("fingerprint", Fingerprint(s.query)),
+		)
+	}
+	txn.prepared = nil
+}
+
+// stmtClosed reports whether stmt has been closed. An open statement of a
+// transaction fails with the context's error before running when given a
+// done context, whereas a closed one reports that it is closed, so probing
+// with a canceled context never reaches the database.
+func stmtClosed(stmt *sql.Stmt) bool {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := stmt.ExecContext(ctx)
+	return !errors.Is(err, context.Canceled)
+}
@@ -0,0 +1,66 @@
+package txnode_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+
+	"github.com/MartellOnell/txnode"
+)
+
+func TestStmtLeakWarnings(t *testing.T) {
+	const query = "SELECT v FROM t WHERE id = $1"
+	tests := []struct {
+		name     string
+		cache    bool
+		prepares int
+		close    bool
+		want     string
+	}{
+		{"closed", false, 1, true, ""},
+		{"left open", false, 1, false, "prepared statement not closed"},
+		{"cached and used again", true, 2, false, ""},
+		{"cached and never used again", true, 1, false, "cached statement never used again"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+			if err != nil {
+				t.Fatal(err)
+			}
+			mock.ExpectBegin()
+			mock.ExpectPrepare(query)
+			mock.ExpectCommit()
+
+			var buf bytes.Buffer
+			txn := txnode.New(
+				txnode.WithStmtLeakWarnings(),
+				txnode.WithStmtCache(tt.cache),
+				txnode.WithLogger(slog.New(slog.NewTextHandler(&buf, nil))),
+			)
+			txn.SetEnd()
+			for range tt.prepares {
+				stmt, err := txn.PrepareQuery(context.Background(), db, query)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if tt.close {
+					_ = stmt.Close()
+				}
+			}
+			if err := txn.CommitIfNeeded(); err != nil {
+				t.Fatal(err)
+			}
+
+			for _, msg := range []string{"prepared statement not closed", "cached statement never used again"} {
+				if got, want := strings.Contains(buf.String(), msg), msg == tt.want; got != want {
+					t.Errorf("logged %q: %v, want %v\n%s", msg, got, want, buf.String())
+				}
+			}
+		})
+	}
+}
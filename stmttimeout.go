@@ -0,0 +1,54 @@
+package txnode
+
+import (
+	"context"
+	"slices"
+	"time"
+)
+
+// WithPerStatementTimeout runs every statement prepared or executed through
+// the node with a context whose deadline is d after the statement starts, in
+// addition to the deadline of the context the statement is given. For
+// queries, the deadline also covers reading the returned rows. A single
+// runaway statement thus fails with context.DeadlineExceeded instead of
+// stalling the whole chain.
+func WithPerStatementTimeout(d time.Duration) Option {
+	return func(c *config) {
+		c.stmtTimeout = d
+	}
+}
+
+// queryContext derives the context of a query for WithPerStatementTimeout.
+// The result is bound to the context, so the deadline also covers reading
+// it; cancel must be passed to holdStmtCancel once the query has run.
+func (txn *TxNode) queryContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if d := txn.cfg.stmtTimeout; d > 0 {
+		return context.WithTimeout(ctx, d)
+	}
+	return ctx, nil
+}
+
+// stmtCancel releases the context of a query while open reports that its
+// result is still being read.
+type stmtCancel struct {
+	cancel context.CancelFunc
+	open   func() bool
+}
+
+// holdStmtCancel keeps cancel until the result of the query, for which open
+// reports whether it is still being read, is done or the transaction ends.
+// The contexts of the results done since the last query are released first.
+func (txn *TxNode) holdStmtCancel(cancel context.CancelFunc, open func() bool) {
+	if cancel == nil {
+		return
+	}
+	root := txn.root()
+	root.stmtCancels = slices.DeleteFunc(root.stmtCancels, func(c stmtCancel) bool {
+		if c.open() {
+			return false
+		}
+		c.cancel()
+		return true
+	})
+	root.stmtCancels = append(root.stmtCancels, stmtCancel{cancel: cancel, open: open})
+}
@@ -0,0 +1,68 @@
+package txnode
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestPerStatementTimeoutReleasesQueryContexts(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatal(err)
+	}
+	const queries = 10
+	mock.ExpectBegin()
+	for range queries {
+		mock.ExpectQuery("SELECT v FROM t WHERE id = $1").WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"v"}).AddRow(1))
+		mock.ExpectQuery("SELECT v FROM t").
+			WillReturnRows(sqlmock.NewRows([]string{"v"}).AddRow(1).AddRow(2))
+	}
+	mock.ExpectQuery("SELECT v FROM t").WillReturnRows(sqlmock.NewRows([]string{"v"}).AddRow(1))
+	mock.ExpectQuery("SELECT 1").WillReturnRows(sqlmock.NewRows([]string{"v"}).AddRow(1))
+	mock.ExpectRollback()
+
+	txn := New(WithPerStatementTimeout(time.Minute))
+	ctx := context.Background()
+	for range queries {
+		var v int
+		if err := txn.QueryRowContext(ctx, db, "SELECT v FROM t WHERE id = $1", 1).Scan(&v); err != nil {
+			t.Fatal(err)
+		}
+		rows, err := txn.QueryContext(ctx, db, "SELECT v FROM t")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := rows.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The cancel of the last query is released with the next one.
+	if got := len(txn.stmtCancels); got > 1 {
+		t.Errorf("%d query contexts retained after reading their results, want at most 1", got)
+	}
+
+	open, err := txn.QueryContext(ctx, db, "SELECT v FROM t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer open.Close()
+	row := txn.QueryRowContext(ctx, db, "SELECT 1")
+	if got := len(txn.stmtCancels); got != 2 {
+		t.Errorf("%d query contexts retained with a result open and a row not scanned, want 2", got)
+	}
+	var v int
+	if err := row.Scan(&v); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := txn.RollbackTransaction(); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(txn.stmtCancels); got != 0 {
+		t.Errorf("%d query contexts retained after the rollback, want 0", got)
+	}
+}
@@ -0,0 +1,215 @@
+package txnode
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"sync"
+)
+
+// SyncTxNode is a TxNode that may be shared by several goroutines. TxNode
+// itself is meant to be driven by one goroutine at a time; SyncTxNode
+// serializes every call with a mutex, so that a chain may span goroutines
+// without racing on the state of the node or on the transaction.
+//
+// Use Do to run several calls as one unit, for example to check the state of
+// the node and commit it without another goroutine interleaving. Rows returned
+// by QueryContext hold the transaction's connection until they are closed and
+// must be consumed by one goroutine.
+type SyncTxNode struct {
+	mu  sync.Mutex
+	txn *TxNode
+}
+
+// NewSync creates a new SyncTxNode ready to start a transaction.
+func NewSync(opts ...Option) *SyncTxNode {
+	return &SyncTxNode{txn: New(opts...)}
+}
+
+// Do calls fn with the underlying node while holding the lock of s. fn must
+// not call methods of s, which would deadlock.
+func (s *SyncTxNode) Do(fn func(txn *TxNode) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return fn(s.txn)
+}
+
+// Node returns the underlying node. Calls made on it directly are not
+// serialized with the calls made through s.
+func (s *SyncTxNode) Node() *TxNode {
+	return s.txn
+}
+
+// UnsetEnd marks this node as not being the end of the transaction chain.
+func (s *SyncTxNode) UnsetEnd() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.txn.UnsetEnd()
+}
+
+// SetEnd marks this node as the end of the transaction chain.
+func (s *SyncTxNode) SetEnd() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.txn.SetEnd()
+}
+
+// Tx returns the underlying transaction and whether one has been started.
+func (s *SyncTxNode) Tx() (*sql.Tx, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.txn.Tx()
+}
+
+// ID returns the identifier of the transaction, see TxNode.ID.
+func (s *SyncTxNode) ID() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.txn.ID()
+}
+
+// State returns the state of the transaction, see TxNode.State.
+func (s *SyncTxNode) State() State {
+	return s.txn.State()
+}
+
+// Stats returns the statistics of the transaction, see TxNode.Stats.
+func (s *SyncTxNode) Stats() Stats {
+	return s.txn.Stats()
+}
+
+// Err returns the reason the node was aborted, see TxNode.Err.
+func (s *SyncTxNode) Err() error {
+	return s.txn.Err()
+}
+
+// Begin starts the transaction on db if it has not been started yet.
+func (s *SyncTxNode) Begin(ctx context.Context, db DB) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.txn.Begin(ctx, db)
+}
+
+// PrepareQuery prepares a SQL statement, see TxNode.PrepareQuery.
+func (s *SyncTxNode) PrepareQuery(
+	ctx context.Context,
+	db DB,
+	query string,
+) (*sql.Stmt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.txn.PrepareQuery(ctx, db, query)
+}
+
+// ExecContext executes a statement, see TxNode.ExecContext.
+func (s *SyncTxNode) ExecContext(
+	ctx context.Context,
+	db DB,
+	query string,
+	args ...any,
+) (sql.Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.txn.ExecContext(ctx, db, query, args...)
+}
+
+// QueryContext runs a query, see TxNode.QueryContext.
+func (s *SyncTxNode) QueryContext(
+	ctx context.Context,
+	db DB,
+	query string,
+	args ...any,
+) (*sql.Rows, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.txn.QueryContext(ctx, db, query, args...)
+}
+
+// QueryRowContext runs a query expected to return at most one row, see
+// TxNode.QueryRowContext.
+func (s *SyncTxNode) QueryRowContext(
+	ctx context.Context,
+	db DB,
+	query string,
+	args ...any,
+) *Row {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.txn.QueryRowContext(ctx, db, query, args...)
+}
+
+// Savepoint creates a savepoint, see TxNode.Savepoint.
+func (s *SyncTxNode) Savepoint(ctx context.Context, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.txn.Savepoint(ctx, name)
+}
+
+// RollbackToSavepoint rolls back to a savepoint, see TxNode.RollbackToSavepoint.
+func (s *SyncTxNode) RollbackToSavepoint(ctx context.Context, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.txn.RollbackToSavepoint(ctx, name)
+}
+
+// ReleaseSavepoint releases a savepoint, see TxNode.ReleaseSavepoint.
+func (s *SyncTxNode) ReleaseSavepoint(ctx context.Context, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.txn.ReleaseSavepoint(ctx, name)
+}
+
+// OnCommit registers a hook to run after the commit, see TxNode.OnCommit.
+func (s *SyncTxNode) OnCommit(fn func(ctx context.Context)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.txn.OnCommit(fn)
+}
+
+// BeforeCommit registers a hook to run before the commit, see
+// TxNode.BeforeCommit.
+func (s *SyncTxNode) BeforeCommit(fn func(ctx context.Context, tx *sql.Tx) error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.txn.BeforeCommit(fn)
+}
+
+// OnRollback registers a hook to run after a rollback, see TxNode.OnRollback.
+func (s *SyncTxNode) OnRollback(fn func(reason error)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.txn.OnRollback(fn)
+}
+
+// CommitIfNeeded commits the transaction only if this node is marked as the end.
+func (s *SyncTxNode) CommitIfNeeded() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.txn.CommitIfNeeded()
+}
+
+// RollbackTransaction rolls back the transaction if one exists.
+func (s *SyncTxNode) RollbackTransaction() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.txn.RollbackTransaction()
+}
+
+// RollbackTransactionAndLog rolls back the transaction and logs the error, see
+// TxNode.RollbackTransactionAndLog.
+func (s *SyncTxNode) RollbackTransactionAndLog(
+	log *slog.Logger,
+	op string,
+	err error,
+) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.txn.RollbackTransactionAndLog(log, op, err)
+}
+
+// Close rolls back the transaction if it is still active, see TxNode.Close.
+func (s *SyncTxNode) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.txn.Close()
+}
@@ -0,0 +1,154 @@
+package txnode_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/MartellOnell/txnode"
+	"github.com/MartellOnell/txnode/txtest"
+)
+
+// endCounter counts the end events of a transaction.
+type endCounter struct {
+	txnode.NopObserver
+	committed  atomic.Int32
+	rolledBack atomic.Int32
+}
+
+func (o *endCounter) Committed(context.Context, txnode.EndEvent) {
+	o.committed.Add(1)
+}
+
+func (o *endCounter) RolledBack(context.Context, txnode.EndEvent) {
+	o.rolledBack.Add(1)
+}
+
+func (o *endCounter) ends() int {
+	return int(o.committed.Load() + o.rolledBack.Load())
+}
+
+func TestSyncTxNodeConcurrentStatements(t *testing.T) {
+	const (
+		goroutines = 8
+		statements = 25
+	)
+
+	db := txtest.NewMock().DB()
+	ends := &endCounter{}
+	s := txnode.NewSync(txnode.WithHistory(), txnode.WithObserver(ends))
+	s.SetEnd()
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for range goroutines {
+		wg.Go(func() {
+			for range statements {
+				if _, err := s.ExecContext(ctx, db, "UPDATE t SET v = v + 1"); err != nil {
+					t.Error(err)
+					return
+				}
+				var v int
+				if err := s.QueryRowContext(ctx, db, "SELECT v FROM t").Scan(&v); err != nil && !errors.Is(err, sql.ErrNoRows) {
+					t.Error(err)
+					return
+				}
+				_ = s.State()
+				_ = s.Stats()
+				_ = s.Err()
+				_ = s.ID()
+			}
+		})
+	}
+	wg.Wait()
+
+	if got, want := s.Stats().Statements, 2*goroutines*statements; got != want {
+		t.Errorf("Stats().Statements = %d, want %d", got, want)
+	}
+	if got, want := len(s.Node().History()), 2*goroutines*statements; got != want {
+		t.Errorf("len(History()) = %d, want %d", got, want)
+	}
+	if err := s.CommitIfNeeded(); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.State(); got != txnode.StateCommitted {
+		t.Errorf("State() = %v, want %v", got, txnode.StateCommitted)
+	}
+	if got := ends.committed.Load(); got != 1 {
+		t.Errorf("committed %d times, want 1", got)
+	}
+}
+
+func TestSyncTxNodeCommitRacesWatchdog(t *testing.T) {
+	for range 50 {
+		db := txtest.NewMock().DB()
+		ends := &endCounter{}
+		s := txnode.NewSync(txnode.WithCancelRollback(), txnode.WithObserver(ends))
+		s.SetEnd()
+		ctx, cancel := context.WithCancel(context.Background())
+
+		if _, err := s.ExecContext(ctx, db, "INSERT INTO t (v) VALUES (1)"); err != nil {
+			t.Fatal(err)
+		}
+
+		var wg sync.WaitGroup
+		wg.Go(cancel)
+		var commitErr error
+		wg.Go(func() {
+			commitErr = s.CommitIfNeeded()
+		})
+		wg.Wait()
+
+		switch state := s.State(); state {
+		case txnode.StateCommitted:
+			if commitErr != nil {
+				t.Fatalf("committed with error %v", commitErr)
+			}
+		case txnode.StateRolledBack:
+			// database/sql also rolls back a transaction whose context is
+			// done, which may beat the watchdog.
+			if !errors.Is(commitErr, context.Canceled) && !errors.Is(commitErr, sql.ErrTxDone) {
+				t.Fatalf("rolled back, CommitIfNeeded() = %v, want context.Canceled", commitErr)
+			}
+		default:
+			t.Fatalf("State() = %v after commit and cancel", state)
+		}
+		if err := s.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if got := ends.ends(); got != 1 {
+			t.Fatalf("transaction ended %d times, want 1", got)
+		}
+	}
+}
+
+func TestSyncTxNodeDoubleEnd(t *testing.T) {
+	for range 50 {
+		db := txtest.NewMock().DB()
+		ends := &endCounter{}
+		s := txnode.NewSync(txnode.WithCancelRollback(), txnode.WithObserver(ends))
+		s.SetEnd()
+		ctx, cancel := context.WithCancel(context.Background())
+
+		if err := s.Begin(ctx, db); err != nil {
+			t.Fatal(err)
+		}
+
+		var wg sync.WaitGroup
+		wg.Go(cancel)
+		wg.Go(func() { _ = s.CommitIfNeeded() })
+		wg.Go(func() { _ = s.RollbackTransaction() })
+		wg.Wait()
+
+		if got := ends.ends(); got != 1 {
+			t.Fatalf("transaction ended %d times (committed %d, rolled back %d), want 1",
+				got, ends.committed.Load(), ends.rolledBack.Load())
+		}
+		if state := s.State(); state != txnode.StateCommitted && state != txnode.StateRolledBack {
+			t.Fatalf("State() = %v after the transaction ended", state)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package txnode
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// TempTable is a temporary table created with CreateTempTable.
+type TempTable struct {
+	// Name is the name to refer to the table by in the statements of the
+	// chain, which on SQL Server carries the # prefix of temporary tables.
+	Name string
+}
+
+// String returns the name of the table.
+func (t *TempTable) String() string {
+	return t.Name
+}
+
+// CreateTempTable creates the temporary table name, with the column
+// definitions columns, in the node's transaction, beginning it on db if
+// needed, and drops it when the transaction ends:
+//
+//	tmp, err := txn.CreateTempTable(ctx, db, "staging", "id bigint, payload text")
+//	...
+//	txn.ExecContext(ctx, db, "INSERT INTO orders SELECT * FROM "+tmp.Name)
+//
+// Postgres creates it with ON COMMIT DROP. In SQLite and SQL Server, where
+// the rollback undoes the creation, it is dropped right before the commit.
+// In MySQL, where it does not, it is also dropped right before the rollback.
+// The table is dropped only when the node ends normally; a transaction
+// aborted by its context or its maximum duration leaves a MySQL temporary
+// table to the session. A nil txn fails with ErrNotStarted.
+func (txn *TxNode) CreateTempTable(ctx context.Context, db DB, name, columns string) (*TempTable, error) {
+	if txn == nil {
+		return nil, fmt.Errorf("txnode: create temp table %s: %w", name, ErrNotStarted)
+	}
+	if !isIdentifier(name) {
+		return nil, fmt.Errorf("txnode: invalid table %q", name)
+	}
+
+	d := txn.cfg.dialect
+	t := &TempTable{Name: name}
+	var query string
+	switch d {
+	case DialectPostgres:
+		query = "CREATE TEMPORARY TABLE " + name + " (" + columns + ") ON COMMIT DROP"
+	case DialectSQLServer:
+		t.Name = "#" + name
+		query = "CREATE TABLE " + t.Name + " (" + columns + ")"
+	default:
+		query = "CREATE TEMPORARY TABLE " + name + " (" + columns + ")"
+	}
+	if _, err := txn.ExecContext(ctx, db, query); err != nil {
+		return nil, fmt.Errorf("txnode: create temp table %s: %w", name, err)
+	}
+
+	if d != DialectPostgres {
+		drop := dropTempTableSQL(d, t.Name)
+		txn.BeforeCommit(func(ctx context.Context, tx *sql.Tx) error {
+			_, err := tx.ExecContext(ctx, drop)
+			return err
+		})
+	}
+	if d == DialectMySQL {
+		root := txn.root()
+		root.tempTables = append(root.tempTables, t.Name)
+	}
+	return t, nil
+}
+
+// dropTempTableSQL returns the statement dropping the temporary table name in
+// dialect d.
+func dropTempTableSQL(d Dialect, name string) string {
+	if d == DialectMySQL {
+		return "DROP TEMPORARY TABLE IF EXISTS " + name
+	}
+	return "DROP TABLE IF EXISTS " + name
+}
+
+// dropTempTables drops the temporary tables that the rollback does not undo,
+// before the rollback.
+func (txn *TxNode) dropTempTables() {
+	ctx := txn.hookContext()
+	for _, name := range txn.tempTables {
+		_, _ = txn.tx.ExecContext(ctx, dropTempTableSQL(txn.cfg.dialect, name))
+	}
+	txn.tempTables = nil
+}
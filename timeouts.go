@@ -0,0 +1,68 @@
+package txnode
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// WithStatementTimeout limits every statement of the node's transaction to d
+// on the server side, by issuing SET LOCAL statement_timeout right after the
+// transaction begins. Unlike a context deadline, the limit is enforced by the
+// server, so a chain cannot hold its locks indefinitely even if its context
+// is generous.
+//
+// MySQL and SQL Server have no transaction-scoped settings: in MySQL the
+// session variable max_execution_time is set instead, which only bounds
+// SELECT statements, and restored before the transaction ends, like the
+// other session settings of the node, see SetLocal. SQL Server and SQLite
+// have no such limit; beginning the transaction there fails with
+// ErrUnsupported.
+func WithStatementTimeout(d time.Duration) Option {
+	return func(c *config) {
+		c.statementTimeout = d
+	}
+}
+
+// WithLockTimeout limits the time every statement of the node's transaction
+// waits for a lock to d, by issuing SET LOCAL lock_timeout right after the
+// transaction begins. In MySQL the session variable innodb_lock_wait_timeout,
+// in whole seconds, is set instead and in SQL Server SET LOCK_TIMEOUT is
+// issued, both restored before the transaction ends, see
+// WithStatementTimeout. SQLite has no lock timeout and fails with
+// ErrUnsupported.
+func WithLockTimeout(d time.Duration) Option {
+	return func(c *config) {
+		c.lockTimeout = d
+	}
+}
+
+// initTx applies the transaction-scoped settings of the node to tx right
+// after it began.
+func (txn *TxNode) initTx(ctx context.Context, tx *sql.Tx) error {
+	settings := []struct {
+		name string
+		d    time.Duration
+	}{
+		{"statement_timeout", txn.cfg.statementTimeout},
+		{"lock_timeout", txn.cfg.lockTimeout},
+	}
+	for _, s := range settings {
+		if s.d <= 0 {
+			continue
+		}
+		if txn.cfg.dialect != DialectPostgres {
+			if err := txn.setSessionTimeout(ctx, tx, s.name, s.d); err != nil {
+				return fmt.Errorf("txnode: set %s: %w", s.name, err)
+			}
+			continue
+		}
+		ms := max(s.d.Milliseconds(), 1)
+		if _, err := tx.ExecContext(ctx, "SET LOCAL "+s.name+" = "+strconv.FormatInt(ms, 10)); err != nil {
+			return fmt.Errorf("txnode: set %s: %w", s.name, err)
+		}
+	}
+	return nil
+}
@@ -6,7 +6,11 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // TxNode represents a node in a transaction chain.
@@ -15,16 +19,110 @@ type TxNode struct {
 	isStart bool
 	tx      *sql.Tx
 	isEnd   bool
+	cfg     config
+	// id identifies the transaction, see ID.
+	id string
+
+	// conn is the connection the transaction is begun on, see NewOnConn.
+	// onConn remains set once conn has been closed.
+	conn   *sql.Conn
+	onConn bool
+	// cancel releases the context derived for WithTimeout.
+	cancel context.CancelFunc
+	// releaseSlot releases the slot taken for WithMaxConcurrentTx.
+	releaseSlot func()
+	// stopWatch stops the watchdog started for WithCancelRollback.
+	stopWatch func() bool
+	// stopTimer stops the timer armed for WithMaxDuration.
+	stopTimer func() bool
+	// ctx and began are the context and the time the transaction was begun with.
+	ctx   context.Context
+	began time.Time
+	// beforeCommit and onCommit hold the hooks registered with BeforeCommit
+	// and OnCommit.
+	beforeCommit []func(ctx context.Context, tx *sql.Tx) error
+	onCommit     []func(ctx context.Context)
+	// invalidate holds the cache keys recorded with InvalidateKey.
+	invalidate []string
+	// named holds the transactions on the databases registered with
+	// WithDatabase.
+	named []*namedTx
+	// tempTables holds the temporary tables created with CreateTempTable
+	// that must be dropped before a rollback.
+	tempTables []string
+	// sessionResets restore the session settings changed for the transaction,
+	// see SetLocal.
+	sessionResets []sessionReset
+	// badConn is set once a session setting could not be restored, so that
+	// conn is discarded rather than returned to the pool.
+	badConn atomic.Bool
+	// heldOpen is set once CommitIfNeeded skipped the commit of a node
+	// created with WithRollbackOnly, see Close.
+	heldOpen bool
+	// stmtCache holds statements prepared with WithStmtCache, keyed by query.
+	stmtCache map[string]*sql.Stmt
+	// stmts holds the statements to close when the transaction ends.
+	stmts []*sql.Stmt
+	// stmtCancels release the contexts derived for WithPerStatementTimeout.
+	stmtCancels []stmtCancel
+	// openRows holds the result sets of QueryContext, for
+	// WithUnclosedRowsCheck.
+	openRows []openRows
+	// slowQueries holds the slow queries whose plan is yet to be captured, for
+	// WithSlowQueryExplain.
+	slowQueries []*slowQuery
+	// prepared holds the statements prepared through the node, for
+	// WithStmtLeakWarnings.
+	prepared []openStmt
+	// leak is the token of the leak check armed when the transaction began.
+	leak *leakToken
+	// debug holds what WithDebug recorded about the transaction.
+	debug *debugInfo
+
+	// parent and savepoint are set on nodes created with the Nested
+	// propagation mode, which share the parent's transaction.
+	parent     *TxNode
+	savepoint  string
+	savepoints int
+	// cursors counts the cursors declared with DeclareCursor.
+	cursors int
+	// beginCaller is where the transaction was begun, for WithPostMortem.
+	beginCaller string
+
+	// mu guards the fields below, which are also accessed from the
+	// goroutines of the watchdog and the maximum duration timer.
+	mu      sync.Mutex
+	aborted error
+	// committing is set once CommitIfNeeded sends the commit, or
+	// PrepareTransaction prepares the transaction, which then reports how
+	// the transaction ended even if it is aborted meanwhile.
+	committing bool
+	recent     []string
+	history    []HistoryEntry
+	// endedBy holds the endedDepth callers that committed, rolled back or
+	// prepared the transaction, see TxClosedError.
+	endedBy    [maxEndFrames]uintptr
+	endedDepth int
+	stats      Stats
+	ended      time.Time
+	state      State
+	onRollback []func(reason error)
 }
 
+var _ io.Closer = (*TxNode)(nil)
+
 var (
 	ErrTransactionArgsMismatch = errors.New("transaction args mismatch")
+	ErrNotStarted              = errors.New("transaction not started")
+	ErrInvalidSavepointName    = errors.New("invalid savepoint name")
+	ErrAborted                 = errors.New("transaction aborted")
 )
 
 // New creates a new TxNode ready to start a transaction.
-func New() *TxNode {
+func New(opts ...Option) *TxNode {
 	return &TxNode{
 		isStart: true,
+		cfg:     newConfig(opts),
 	}
 }
 
@@ -38,11 +136,29 @@ func (txn *TxNode) SetEnd() {
 	txn.isEnd = true
 }
 
+// Tx returns the underlying transaction and whether one has been started.
+func (txn *TxNode) Tx() (*sql.Tx, bool) {
+	if txn == nil || txn.tx == nil {
+		return nil, false
+	}
+	return txn.tx, true
+}
+
+// Begin starts the transaction on db if this node has not started one yet.
+// Other methods begin lazily, so calling Begin is only needed to start the
+// transaction before the first statement. It does nothing if txn is nil.
+func (txn *TxNode) Begin(ctx context.Context, db DB) error {
+	if txn == nil {
+		return nil
+	}
+	return txn.begin(ctx, db)
+}
+
 // PrepareQuery prepares a SQL statement. It begins a transaction on first call
 // or reuses the existing transaction. Returns nil if txn is nil (non-transactional mode).
 func (txn *TxNode) PrepareQuery(
 	ctx context.Context,
-	db *sql.DB,
+	db DB,
 	query string,
 ) (*sql.Stmt, error) {
 	if txn == nil {
@@ -50,56 +166,399 @@ func (txn *TxNode) PrepareQuery(
 		return stmt, err
 	}
 
-	if txn.isStart {
-		tx, err := db.BeginTx(ctx, nil)
-		if err != nil {
-			return nil, err
+	tx, err := txn.activeTx(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	query = txn.rebind(query)
+
+	txn.debugPrepare(query)
+	root := txn.root()
+	if txn.cfg.stmtCache {
+		if stmt, ok := root.stmtCache[query]; ok {
+			txn.record(query)
+			root.reuseStmt(stmt)
+			return stmt, nil
 		}
+	}
 
-		txn.isStart = false
-		txn.tx = tx
+	var stmt *sql.Stmt
+	err = txn.runStmt(ctx, stmtPrepare, query, nil, func(ctx context.Context) (int64, error) {
+		stmt, err = tx.PrepareContext(ctx, query)
+		return -1, err
+	})
+	if err != nil {
+		return nil, err
+	}
 
-		stmt, err := tx.PrepareContext(ctx, query)
-		return stmt, err
+	if txn.cfg.stmtCache {
+		if root.stmtCache == nil {
+			root.stmtCache = make(map[string]*sql.Stmt)
+		}
+		root.stmtCache[query] = stmt
 	}
+	if txn.cfg.trackStmt {
+		root.stmts = append(root.stmts, stmt)
+	}
+	txn.watchStmt(stmt, query)
+	return stmt, nil
+}
 
-	if txn.tx != nil {
-		stmt, err := txn.tx.PrepareContext(ctx, query)
-		return stmt, err
+// activeTx returns the chain's transaction, beginning it on db if needed.
+func (txn *TxNode) activeTx(ctx context.Context, db DB) (*sql.Tx, error) {
+	if err := txn.begin(ctx, db); err != nil {
+		return nil, err
+	}
+
+	if err := txn.root().Err(); err != nil {
+		return nil, err
+	}
+
+	if err := txn.closedErr(); err != nil {
+		return nil, err
+	}
+
+	if txn.tx == nil {
+		return nil, ErrTransactionArgsMismatch
+	}
+
+	return txn.tx, nil
+}
+
+// begin starts the transaction on db if this node has not started one yet.
+func (txn *TxNode) begin(ctx context.Context, db DB) error {
+	if !txn.isStart {
+		return nil
+	}
+
+	if txn.cfg.timeout > 0 {
+		ctx, txn.cancel = context.WithTimeout(ctx, txn.cfg.timeout)
+	}
+
+	if txn.id == "" {
+		txn.id = newID()
+	}
+
+	var beginner Beginner = db
+	if txn.onConn {
+		if txn.conn == nil {
+			return &BeginError{ID: txn.id, Err: sql.ErrConnDone}
+		}
+		beginner = txn.conn
 	}
 
-	return nil, ErrTransactionArgsMismatch
+	var tx *sql.Tx
+	start := time.Now()
+	err := txn.cfg.manager.admit()
+	if err == nil {
+		err = txn.cfg.breaker.allow()
+	}
+	if err == nil {
+		txn.releaseSlot, err = txn.cfg.limiter.acquire(ctx)
+	}
+	if err == nil {
+		err = txn.retry(ctx, txn.beginRetryPolicy(), func() (err error) {
+			if err := txn.cfg.faults.inject(FaultBegin, ""); err != nil {
+				return err
+			}
+			tx, err = txn.beginTx(ctx, beginner)
+			return err
+		})
+		txn.cfg.breaker.record(err, txn.cfg.classifier)
+	}
+	if err == nil {
+		if err = txn.initTx(ctx, tx); err != nil {
+			txn.resetSession(tx)
+			_ = tx.Rollback()
+		}
+	}
+	txn.notifyBegin(ctx, start, err)
+	txn.logBegin(ctx, start, err)
+	if err != nil {
+		if txn.cancel != nil {
+			txn.cancel()
+			txn.cancel = nil
+		}
+		if txn.releaseSlot != nil {
+			txn.releaseSlot()
+			txn.releaseSlot = nil
+		}
+		txn.closeConn()
+		return &BeginError{ID: txn.id, Err: err}
+	}
+
+	txn.isStart = false
+	txn.tx = tx
+	txn.ctx = ctx
+	txn.began = start
+	txn.setState(StateActive)
+	txn.debugBegin()
+	if txn.cfg.postMortem > 0 {
+		txn.beginCaller = callerOutside()
+	}
+	txn.watch(ctx)
+	txn.startTimer()
+	txn.watchLeak()
+	if !txn.cfg.manager.track(txn) {
+		reason := fmt.Errorf("%w: %w", ErrAborted, ErrShutdown)
+		txn.abort(reason)
+		return &BeginError{ID: txn.id, Err: reason}
+	}
+	txn.register()
+	return nil
 }
 
-// RollbackTransaction rolls back the transaction if one exists.
+// RollbackTransaction rolls back the transaction if one exists. Rolling back a
+// node whose transaction has already ended does nothing, so that the call can
+// be deferred unconditionally, unless WithStrictCompletion is set.
 func (txn *TxNode) RollbackTransaction() error {
-	if txn == nil || txn.tx == nil {
+	return txn.rollback(nil)
+}
+
+// Close rolls back the transaction if it is still active and does nothing
+// otherwise, even with WithStrictCompletion. It lets callers defer Close right
+// after New so that the transaction is never leaked:
+//
+//	txn := txnode.New()
+//	defer txn.Close()
+func (txn *TxNode) Close() error {
+	if txn == nil {
+		return nil
+	}
+	if txn.tx == nil {
+		txn.rollbackDatabases()
+		txn.closeConn()
 		return nil
 	}
+	if txn.State() != StateActive && txn.Err() == nil {
+		return nil
+	}
+	txn.heldOpen = false
+	return txn.rollback(nil)
+}
 
-	return txn.tx.Rollback()
+// rollback rolls back the transaction and runs the rollback hooks with reason.
+func (txn *TxNode) rollback(reason error) error {
+	if txn == nil {
+		return nil
+	}
+	if txn.tx == nil {
+		txn.rollbackDatabases()
+		txn.closeConn()
+		return nil
+	}
+
+	if txn.Err() == nil {
+		if done, err := txn.finished(false); done {
+			return err
+		}
+	}
+	if txn.heldOpen {
+		return nil
+	}
+
+	if txn.savepoint != "" {
+		txn.beforeCommit, txn.onCommit = nil, nil
+		err := txn.parent.RollbackToSavepoint(context.Background(), txn.savepoint)
+		txn.setState(StateRolledBack)
+		txn.runRollbackHooks(reason)
+		return err
+	}
+
+	defer txn.release()
+	if txn.Err() != nil {
+		return nil
+	}
+
+	_ = txn.checkOpenRows(false)
+	txn.explainQueries(txn.hookContext(), true)
+	txn.checkStmtLeaks()
+	txn.dropTempTables()
+	txn.resetSession(txn.tx)
+	err := txn.tx.Rollback()
+	if fault := txn.cfg.faults.inject(FaultRollback, ""); fault != nil {
+		err = fault
+	}
+	txn.notifyEnd(false, reason)
+	txn.runRollbackHooks(reason)
+	if err != nil {
+		return &RollbackError{ID: txn.id, Err: err}
+	}
+	return nil
 }
 
 // CommitIfNeeded commits the transaction only if this node is marked as the end.
+// Committing a node again after a successful commit does nothing, unless
+// WithStrictCompletion is set; committing it after a rollback fails with an
+// error wrapping sql.ErrTxDone.
 func (txn *TxNode) CommitIfNeeded() error {
-	if txn == nil || txn.tx == nil || !txn.isEnd {
+	if txn == nil || !txn.isEnd {
 		return nil
 	}
+	if txn.tx == nil {
+		return txn.commitDatabases()
+	}
+
+	if txn.Err() == nil {
+		if done, err := txn.finished(true); done {
+			return err
+		}
+	}
 
-	return txn.tx.Commit()
+	if txn.savepoint != "" {
+		if err := txn.parent.ReleaseSavepoint(context.Background(), txn.savepoint); err != nil {
+			return err
+		}
+		txn.setState(StateCommitted)
+		txn.parent.beforeCommit = append(txn.parent.beforeCommit, txn.beforeCommit...)
+		txn.parent.onCommit = append(txn.parent.onCommit, txn.onCommit...)
+		txn.beforeCommit, txn.onCommit = nil, nil
+		txn.handOverRollbackHooks()
+		return nil
+	}
+
+	if txn.cfg.dryRun {
+		return txn.rollbackDryRun()
+	}
+
+	if txn.cfg.rollbackOnly && txn.Err() == nil {
+		txn.heldOpen = true
+		return nil
+	}
+
+	defer txn.release()
+	if err := txn.Err(); err != nil {
+		return err
+	}
+
+	if txn.cfg.external {
+		txn.setState(StateHandedOff)
+		return nil
+	}
+
+	if err := txn.checkOpenRows(true); err != nil {
+		err = &CommitError{ID: txn.id, Phase: PhaseCommit, Err: err}
+		txn.resetSession(txn.tx)
+		_ = txn.tx.Rollback()
+		txn.notifyEnd(false, err)
+		txn.runRollbackHooks(err)
+		return err
+	}
+
+	txn.explainQueries(txn.hookContext(), true)
+	if err := txn.runBeforeCommitHooks(); err != nil {
+		return err
+	}
+
+	txn.resetSession(txn.tx)
+	txn.checkStmtLeaks()
+	txn.mu.Lock()
+	txn.committing = true
+	txn.mu.Unlock()
+	err := txn.cfg.faults.inject(FaultCommit, "")
+	if err != nil {
+		_ = txn.tx.Rollback()
+	} else {
+		err = txn.tx.Commit()
+	}
+	txn.cfg.breaker.record(err, txn.cfg.classifier)
+	if err != nil {
+		// An abort racing the commit rolled the transaction back.
+		if reason := txn.Err(); reason != nil {
+			err = reason
+		}
+		txn.log(slog.LevelError, "commit transaction", slog.Any("error", err))
+		txn.notifyEnd(false, err)
+		txn.runRollbackHooks(err)
+		return &CommitError{ID: txn.id, Phase: PhaseCommit, Err: err}
+	}
+
+	txn.notifyEnd(true, nil)
+	dbErr := txn.commitDatabases()
+	txn.runCommitHooks()
+	return dbErr
+}
+
+// release frees the resources held for the transaction once it has ended.
+func (txn *TxNode) release() {
+	txn.endLeak()
+	txn.rollbackDatabases()
+	if txn.stopWatch != nil {
+		txn.stopWatch()
+		txn.stopWatch = nil
+	}
+	if txn.stopTimer != nil {
+		txn.stopTimer()
+		txn.stopTimer = nil
+	}
+	if txn.cancel != nil {
+		txn.cancel()
+		txn.cancel = nil
+	}
+	for _, stmt := range txn.stmts {
+		_ = stmt.Close()
+	}
+	txn.stmts = nil
+	for _, c := range txn.stmtCancels {
+		c.cancel()
+	}
+	txn.stmtCancels = nil
+	txn.openRows = nil
+	txn.logSlowQueries()
+	txn.prepared = nil
+	txn.stmtCache = nil
+	txn.onCommit = nil
+	txn.closeConn()
+	if txn.releaseSlot != nil {
+		txn.releaseSlot()
+		txn.releaseSlot = nil
+	}
+	txn.untrack()
+}
+
+// closeConn returns the connection of a node created with NewOnConn to the
+// pool.
+func (txn *TxNode) closeConn() {
+	if txn.conn != nil {
+		if txn.badConn.Swap(false) {
+			discardConn(txn.conn)
+		} else {
+			_ = txn.conn.Close()
+		}
+		txn.conn = nil
+	}
 }
 
 // RollbackTransactionAndLog rolls back the transaction and logs both the rollback
-// and the original error. Returns a wrapped error with the operation name.
+// and the original error. Returns a wrapped error with the operation name,
+// joined with the rollback error if the rollback failed as well.
+// A nil log falls back to the logger configured with WithLogger, so nothing is
+// logged if neither is set.
 func (txn *TxNode) RollbackTransactionAndLog(
 	log *slog.Logger,
 	op string,
 	err error,
 ) error {
-	rollbackErr := txn.RollbackTransaction()
+	logger := txn.logger()
+	if log != nil {
+		logger = NewSlogLogger(log)
+	}
+
+	rollbackErr := txn.rollback(err)
+	if re, ok := rollbackErr.(*RollbackError); ok {
+		re.Op = op
+	}
+	if rollbackErr != nil {
+		txn.logTo(logger, slog.LevelError, "rollback transaction",
+			slog.String("op", op), slog.Any("rollback_error", rollbackErr))
+	}
+	txn.logTo(logger, slog.LevelError, "operation failed",
+		slog.String("op", op), slog.Any("error", err))
+
+	err = fmt.Errorf("%s: %w", op, err)
 	if rollbackErr != nil {
-		log.Error(fmt.Sprintf("%s: rollback transaction: %v", op, rollbackErr))
+		return errors.Join(err, rollbackErr)
 	}
-	log.Error(fmt.Sprintf("%s: %v", op, err))
-	return fmt.Errorf("%s: %w", op, err)
+	return err
 }
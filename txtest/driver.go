@@ -0,0 +1,192 @@
+package txtest
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+)
+
+// connector opens connections to the in-memory database of a Mock.
+type connector struct {
+	m *Mock
+}
+
+func (c connector) Connect(context.Context) (driver.Conn, error) {
+	return &conn{m: c.m}, nil
+}
+
+func (c connector) Driver() driver.Driver {
+	return mockDriver{}
+}
+
+// mockDriver only exists to satisfy driver.Connector; connections are opened
+// through the connector.
+type mockDriver struct{}
+
+func (mockDriver) Open(string) (driver.Conn, error) {
+	return nil, errors.New("txtest: open the database with NewMock")
+}
+
+// conn answers every statement from the expectations of its Mock.
+type conn struct {
+	m *Mock
+}
+
+var (
+	_ driver.ConnBeginTx        = (*conn)(nil)
+	_ driver.ConnPrepareContext = (*conn)(nil)
+	_ driver.ExecerContext      = (*conn)(nil)
+	_ driver.QueryerContext     = (*conn)(nil)
+	_ driver.NamedValueChecker  = (*conn)(nil)
+)
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *conn) PrepareContext(_ context.Context, query string) (driver.Stmt, error) {
+	if err := c.m.record(KindPrepare, query, nil).err; err != nil {
+		return nil, err
+	}
+	return &stmt{m: c.m, query: query}, nil
+}
+
+func (c *conn) Close() error {
+	return nil
+}
+
+func (c *conn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *conn) BeginTx(context.Context, driver.TxOptions) (driver.Tx, error) {
+	if err := c.m.record(KindBegin, "", nil).err; err != nil {
+		return nil, err
+	}
+	return tx{m: c.m}, nil
+}
+
+func (c *conn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	return c.m.record(KindExec, query, args).result()
+}
+
+func (c *conn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	return c.m.record(KindQuery, query, args).rows()
+}
+
+// CheckNamedValue accepts arguments of any type, so that tests need not use
+// types a real driver could encode.
+func (c *conn) CheckNamedValue(*driver.NamedValue) error {
+	return nil
+}
+
+// stmt is a statement prepared on a conn.
+type stmt struct {
+	m     *Mock
+	query string
+}
+
+var (
+	_ driver.StmtExecContext  = (*stmt)(nil)
+	_ driver.StmtQueryContext = (*stmt)(nil)
+)
+
+func (s *stmt) Close() error {
+	return nil
+}
+
+func (s *stmt) NumInput() int {
+	return -1
+}
+
+func (s *stmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.ExecContext(context.Background(), named(args))
+}
+
+func (s *stmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.QueryContext(context.Background(), named(args))
+}
+
+func (s *stmt) ExecContext(_ context.Context, args []driver.NamedValue) (driver.Result, error) {
+	return s.m.record(KindExec, s.query, args).result()
+}
+
+func (s *stmt) QueryContext(_ context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	return s.m.record(KindQuery, s.query, args).rows()
+}
+
+// named converts positional arguments of the legacy driver interface.
+func named(args []driver.Value) []driver.NamedValue {
+	nv := make([]driver.NamedValue, len(args))
+	for i, v := range args {
+		nv[i] = driver.NamedValue{Ordinal: i + 1, Value: v}
+	}
+	return nv
+}
+
+// tx records the end of a transaction.
+type tx struct {
+	m *Mock
+}
+
+func (t tx) Commit() error {
+	return t.m.record(KindCommit, "", nil).err
+}
+
+func (t tx) Rollback() error {
+	return t.m.record(KindRollback, "", nil).err
+}
+
+// rows iterates over the rows scripted with WillReturnRows or replayed from
+// a Tape. A non-nil err is returned once the rows are exhausted.
+type rows struct {
+	columns []string
+	values  [][]any
+	err     error
+}
+
+func (r *rows) Columns() []string {
+	return r.columns
+}
+
+func (r *rows) Close() error {
+	r.values = nil
+	return nil
+}
+
+func (r *rows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		if r.err != nil {
+			return r.err
+		}
+		return io.EOF
+	}
+	row := r.values[0]
+	r.values = r.values[1:]
+	for i := range dest {
+		if i >= len(row) {
+			dest[i] = nil
+			continue
+		}
+		v, err := driver.DefaultParameterConverter.ConvertValue(row[i])
+		if err != nil {
+			return err
+		}
+		dest[i] = v
+	}
+	return nil
+}
+
+// result is the driver.Result scripted with WillReturnResult.
+type result struct {
+	lastID, affected int64
+}
+
+func (r result) LastInsertId() (int64, error) {
+	return r.lastID, nil
+}
+
+func (r result) RowsAffected() (int64, error) {
+	return r.affected, nil
+}
@@ -0,0 +1,243 @@
+// Package txtest provides test doubles for code that runs its statements
+// through a txnode.Node, so that it can be unit tested without a database.
+//
+//	m := txtest.NewMock()
+//	m.On("SELECT name FROM users WHERE id = $1").WillReturnRows([]string{"name"}, []any{"alice"})
+//	m.SetEnd()
+//	err := svc.Rename(ctx, m, 1)
+//	// inspect m.Calls(), m.Committed()
+package txtest
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"strings"
+	"sync"
+
+	"github.com/MartellOnell/txnode"
+)
+
+// Kind is the kind of a call the Mock recorded.
+type Kind string
+
+const (
+	KindBegin    Kind = "begin"
+	KindPrepare  Kind = "prepare"
+	KindExec     Kind = "exec"
+	KindQuery    Kind = "query"
+	KindCommit   Kind = "commit"
+	KindRollback Kind = "rollback"
+)
+
+// Call is a call the Mock received. Query and Args are empty for begin,
+// commit and rollback.
+type Call struct {
+	Kind  Kind
+	Query string
+	Args  []any
+}
+
+// Mock is a txnode.Node backed by an in-memory database/sql driver instead of
+// a real database. Every db passed to its Node methods is ignored in favor of
+// that driver, so callers may pass nil. Other TxNode methods are promoted from
+// the embedded node and should be given DB.
+//
+// Statements are answered from the expectations registered with On. A
+// statement without one succeeds: an exec affects no rows and a query returns
+// no rows.
+type Mock struct {
+	*txnode.TxNode
+
+	db *sql.DB
+
+	mu        sync.Mutex
+	calls     []Call
+	expect    map[string][]*Result
+	beginErr  error
+	commitErr error
+}
+
+var _ txnode.Node = (*Mock)(nil)
+
+// NewMock returns a Mock whose node is created with opts.
+func NewMock(opts ...txnode.Option) *Mock {
+	m := &Mock{
+		TxNode: txnode.New(opts...),
+		expect: make(map[string][]*Result),
+	}
+	m.db = sql.OpenDB(connector{m: m})
+	return m
+}
+
+// DB returns the in-memory database the Mock runs its statements on.
+func (m *Mock) DB() *sql.DB {
+	return m.db
+}
+
+// On registers an expectation for query, matched after collapsing runs of
+// whitespace, and returns it to be scripted. Several expectations for the
+// same query answer its executions in the order they were registered, the
+// last one answering all remaining executions.
+func (m *Mock) On(query string) *Result {
+	r := &Result{}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := normalize(query)
+	m.expect[key] = append(m.expect[key], r)
+	return r
+}
+
+// FailBegin makes beginning the transaction fail with err.
+func (m *Mock) FailBegin(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.beginErr = err
+}
+
+// FailCommit makes committing the transaction fail with err.
+func (m *Mock) FailCommit(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.commitErr = err
+}
+
+// Calls returns the calls received so far, in order.
+func (m *Mock) Calls() []Call {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Call(nil), m.calls...)
+}
+
+// Queries returns the text of the statements executed or queried so far, in
+// order.
+func (m *Mock) Queries() []string {
+	var queries []string
+	for _, c := range m.Calls() {
+		if c.Kind == KindExec || c.Kind == KindQuery {
+			queries = append(queries, c.Query)
+		}
+	}
+	return queries
+}
+
+// Committed reports whether the transaction was committed successfully.
+func (m *Mock) Committed() bool {
+	return m.ended(KindCommit) && m.commitError() == nil
+}
+
+// RolledBack reports whether the transaction was rolled back.
+func (m *Mock) RolledBack() bool {
+	return m.ended(KindRollback)
+}
+
+func (m *Mock) ended(kind Kind) bool {
+	for _, c := range m.Calls() {
+		if c.Kind == kind {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Mock) commitError() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.commitErr
+}
+
+// PrepareQuery prepares query on the Mock's database, ignoring db.
+func (m *Mock) PrepareQuery(ctx context.Context, _ txnode.DB, query string) (*sql.Stmt, error) {
+	return m.TxNode.PrepareQuery(ctx, m.db, query)
+}
+
+// ExecContext executes query on the Mock's database, ignoring db.
+func (m *Mock) ExecContext(ctx context.Context, _ txnode.DB, query string, args ...any) (sql.Result, error) {
+	return m.TxNode.ExecContext(ctx, m.db, query, args...)
+}
+
+// QueryContext runs query on the Mock's database, ignoring db.
+func (m *Mock) QueryContext(ctx context.Context, _ txnode.DB, query string, args ...any) (*sql.Rows, error) {
+	return m.TxNode.QueryContext(ctx, m.db, query, args...)
+}
+
+// QueryRowContext runs query on the Mock's database, ignoring db.
+func (m *Mock) QueryRowContext(ctx context.Context, _ txnode.DB, query string, args ...any) *txnode.Row {
+	return m.TxNode.QueryRowContext(ctx, m.db, query, args...)
+}
+
+// record appends a call and returns the expectation answering it.
+func (m *Mock) record(kind Kind, query string, args []driver.NamedValue) *Result {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c := Call{Kind: kind, Query: query}
+	for _, a := range args {
+		c.Args = append(c.Args, a.Value)
+	}
+	m.calls = append(m.calls, c)
+
+	switch kind {
+	case KindBegin:
+		return &Result{err: m.beginErr}
+	case KindCommit:
+		return &Result{err: m.commitErr}
+	case KindExec, KindQuery:
+		key := normalize(query)
+		if rs := m.expect[key]; len(rs) > 0 {
+			if len(rs) > 1 {
+				m.expect[key] = rs[1:]
+			}
+			return rs[0]
+		}
+	}
+	return &Result{}
+}
+
+// normalize collapses runs of whitespace in query.
+func normalize(query string) string {
+	return strings.Join(strings.Fields(query), " ")
+}
+
+// Result scripts the answer to a statement registered with On.
+type Result struct {
+	columns  []string
+	values   [][]any
+	lastID   int64
+	affected int64
+	err      error
+}
+
+// WillReturnRows makes a query return rows with the given columns.
+func (r *Result) WillReturnRows(columns []string, rows ...[]any) *Result {
+	r.columns = columns
+	r.values = rows
+	return r
+}
+
+// WillReturnResult makes an exec report lastInsertID and rowsAffected.
+func (r *Result) WillReturnResult(lastInsertID, rowsAffected int64) *Result {
+	r.lastID = lastInsertID
+	r.affected = rowsAffected
+	return r
+}
+
+// WillReturnError makes the statement fail with err.
+func (r *Result) WillReturnError(err error) *Result {
+	r.err = err
+	return r
+}
+
+func (r *Result) result() (driver.Result, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	return result{lastID: r.lastID, affected: r.affected}, nil
+}
+
+func (r *Result) rows() (driver.Rows, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	return &rows{columns: r.columns, values: append([][]any(nil), r.values...)}, nil
+}
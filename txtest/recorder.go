@@ -0,0 +1,150 @@
+package txtest
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/MartellOnell/txnode"
+)
+
+// Recorder records, through an observer it attaches to nodes, how the transactions of the
+// nodes it is attached to ended and which statements they ran, for tests to
+// assert transactional behavior rather than only the final database state.
+//
+//	rec := txtest.NewRecorder()
+//	txn := txnode.New(rec.Option())
+//	...
+//	rec.AssertCommitted(t)
+//	rec.AssertQueriesInOrder(t, "INSERT INTO orders", "UPDATE stock")
+type Recorder struct {
+	mu        sync.Mutex
+	began     int
+	committed int
+	rolled    int
+	last      Kind
+	queries   []string
+}
+
+// NewRecorder returns an empty Recorder.
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+// Option returns the option attaching r to a node.
+func (r *Recorder) Option() txnode.Option {
+	return txnode.WithObserver(observer{r})
+}
+
+// observer feeds the events of a node to its Recorder.
+type observer struct {
+	r *Recorder
+}
+
+var _ txnode.Observer = observer{}
+
+func (o observer) TxBegan(_ context.Context, ev txnode.BeginEvent) {
+	if ev.Err != nil {
+		return
+	}
+	o.r.mu.Lock()
+	defer o.r.mu.Unlock()
+	o.r.began++
+}
+
+func (o observer) StmtPrepared(context.Context, txnode.StmtEvent) {}
+
+func (o observer) StmtExecuted(_ context.Context, ev txnode.StmtEvent) {
+	o.r.mu.Lock()
+	defer o.r.mu.Unlock()
+	o.r.queries = append(o.r.queries, ev.Query)
+}
+
+func (o observer) Committed(context.Context, txnode.EndEvent) {
+	o.r.mu.Lock()
+	defer o.r.mu.Unlock()
+	o.r.committed++
+	o.r.last = KindCommit
+}
+
+func (o observer) RolledBack(context.Context, txnode.EndEvent) {
+	o.r.mu.Lock()
+	defer o.r.mu.Unlock()
+	o.r.rolled++
+	o.r.last = KindRollback
+}
+
+// Committed reports whether the last transaction that ended was committed.
+func (r *Recorder) Committed() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.last == KindCommit
+}
+
+// RolledBack reports whether the last transaction that ended was rolled back.
+func (r *Recorder) RolledBack() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.last == KindRollback
+}
+
+// Leaked reports whether a transaction was begun and has neither been
+// committed nor rolled back.
+func (r *Recorder) Leaked() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.began > r.committed+r.rolled
+}
+
+// Queries returns the statements executed so far, in order.
+func (r *Recorder) Queries() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.queries...)
+}
+
+// AssertCommitted fails t unless the last transaction was committed and none
+// leaked.
+func (r *Recorder) AssertCommitted(t testing.TB) {
+	t.Helper()
+	if !r.Committed() {
+		t.Errorf("txtest: transaction not committed")
+	}
+	r.AssertNotLeaked(t)
+}
+
+// AssertRolledBack fails t unless the last transaction was rolled back and
+// none leaked.
+func (r *Recorder) AssertRolledBack(t testing.TB) {
+	t.Helper()
+	if !r.RolledBack() {
+		t.Errorf("txtest: transaction not rolled back")
+	}
+	r.AssertNotLeaked(t)
+}
+
+// AssertNotLeaked fails t if a transaction is still open.
+func (r *Recorder) AssertNotLeaked(t testing.TB) {
+	t.Helper()
+	if r.Leaked() {
+		t.Errorf("txtest: transaction neither committed nor rolled back")
+	}
+}
+
+// AssertQueriesInOrder fails t unless the executed statements contain the
+// given ones in this order, possibly with others in between. Each of queries
+// matches a statement that contains it once whitespace is collapsed.
+func (r *Recorder) AssertQueriesInOrder(t testing.TB, queries ...string) {
+	t.Helper()
+	ran := r.Queries()
+	i := 0
+	for _, q := range ran {
+		if i < len(queries) && strings.Contains(normalize(q), normalize(queries[i])) {
+			i++
+		}
+	}
+	if i < len(queries) {
+		t.Errorf("txtest: query %q not run in order, ran:\n\t%s", queries[i], strings.Join(ran, "\n\t"))
+	}
+}
@@ -0,0 +1,26 @@
+package txtest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/MartellOnell/txnode"
+)
+
+// NewRollbackOnly returns a node created with txnode.WithRollbackOnly and
+// opts, whose transaction is begun on db right away and rolled back when t
+// and its subtests complete. Chains run through it behave as in production,
+// including CommitIfNeeded reporting success, yet leave db untouched.
+func NewRollbackOnly(t testing.TB, db txnode.DB, opts ...txnode.Option) *txnode.TxNode {
+	t.Helper()
+	txn := txnode.New(append(opts[:len(opts):len(opts)], txnode.WithRollbackOnly())...)
+	if err := txn.Begin(context.Background(), db); err != nil {
+		t.Fatalf("txtest: begin rollback-only transaction: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := txn.Close(); err != nil {
+			t.Errorf("txtest: roll back transaction: %v", err)
+		}
+	})
+	return txn
+}
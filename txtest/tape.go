@@ -0,0 +1,641 @@
+package txtest
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Interaction is one call to the database stored on a Tape, with what it
+// returned.
+type Interaction struct {
+	Kind  Kind   `json:"kind"`
+	Query string `json:"query,omitempty"`
+	// Args is a fingerprint of the arguments, compared on replay.
+	Args         string    `json:"args,omitempty"`
+	Columns      []string  `json:"columns,omitempty"`
+	Rows         [][]Value `json:"rows,omitempty"`
+	LastInsertID int64     `json:"last_insert_id,omitempty"`
+	RowsAffected int64     `json:"rows_affected,omitempty"`
+	// Err is the message of the error the call failed with.
+	Err string `json:"error,omitempty"`
+}
+
+// Value is a column value stored on a Tape. It keeps the driver.Value type
+// of the value across encoding.
+type Value struct {
+	V driver.Value
+}
+
+type taggedValue struct {
+	Type  string          `json:"t"`
+	Value json.RawMessage `json:"v,omitempty"`
+}
+
+func (v Value) MarshalJSON() ([]byte, error) {
+	var typ string
+	switch x := v.V.(type) {
+	case nil:
+		return json.Marshal(taggedValue{Type: "null"})
+	case int64:
+		typ = "int"
+	case float64:
+		typ = "float"
+	case bool:
+		typ = "bool"
+	case string:
+		typ = "string"
+	case []byte:
+		typ = "bytes"
+	case time.Time:
+		typ = "time"
+		v.V = x.Format(time.RFC3339Nano)
+	default:
+		return nil, fmt.Errorf("txtest: cannot record value of type %T", v.V)
+	}
+	raw, err := json.Marshal(v.V)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(taggedValue{Type: typ, Value: raw})
+}
+
+func (v *Value) UnmarshalJSON(data []byte) error {
+	var tv taggedValue
+	if err := json.Unmarshal(data, &tv); err != nil {
+		return err
+	}
+	var err error
+	switch tv.Type {
+	case "null":
+		v.V = nil
+	case "int":
+		var x int64
+		err = json.Unmarshal(tv.Value, &x)
+		v.V = x
+	case "float":
+		var x float64
+		err = json.Unmarshal(tv.Value, &x)
+		v.V = x
+	case "bool":
+		var x bool
+		err = json.Unmarshal(tv.Value, &x)
+		v.V = x
+	case "string":
+		var x string
+		err = json.Unmarshal(tv.Value, &x)
+		v.V = x
+	case "bytes":
+		var x []byte
+		err = json.Unmarshal(tv.Value, &x)
+		v.V = x
+	case "time":
+		var s string
+		if err = json.Unmarshal(tv.Value, &s); err == nil {
+			v.V, err = time.Parse(time.RFC3339Nano, s)
+		}
+	default:
+		err = fmt.Errorf("txtest: unknown value type %q", tv.Type)
+	}
+	return err
+}
+
+// Tape holds the interactions of a chain with its database, recorded with
+// Record and served back with Replay, so that complex chains can be tested
+// deterministically without a database. Interactions are replayed in the
+// order they were recorded, which makes tapes suited to chains running on a
+// single goroutine.
+type Tape struct {
+	mu           sync.Mutex
+	interactions []*Interaction
+	pos          int
+}
+
+// Record returns a database that runs every statement on the database named by
+// dsn through d, recording the interactions on the returned tape.
+func Record(d driver.Driver, dsn string) (*sql.DB, *Tape, error) {
+	var c driver.Connector = dsnConnector{d: d, dsn: dsn}
+	if dc, ok := d.(driver.DriverContext); ok {
+		var err error
+		if c, err = dc.OpenConnector(dsn); err != nil {
+			return nil, nil, err
+		}
+	}
+	t := &Tape{}
+	return sql.OpenDB(recordConnector{c: c, tape: t}), t, nil
+}
+
+// LoadTape reads a tape saved with Save.
+func LoadTape(path string) (*Tape, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	t := &Tape{}
+	if err := json.Unmarshal(data, &t.interactions); err != nil {
+		return nil, fmt.Errorf("txtest: load tape %s: %w", path, err)
+	}
+	return t, nil
+}
+
+// Save writes the recorded interactions to path as JSON.
+func (t *Tape) Save(path string) error {
+	t.mu.Lock()
+	data, err := json.MarshalIndent(t.interactions, "", "\t")
+	t.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("txtest: save tape %s: %w", path, err)
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// Interactions returns the interactions on the tape.
+func (t *Tape) Interactions() []Interaction {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	out := make([]Interaction, len(t.interactions))
+	for i, ia := range t.interactions {
+		out[i] = *ia
+	}
+	return out
+}
+
+// Replay returns a database answering from the tape instead of a real
+// database. A call other than the next one on the tape, by kind, query or
+// arguments, fails with an error describing both.
+func (t *Tape) Replay() *sql.DB {
+	t.mu.Lock()
+	t.pos = 0
+	t.mu.Unlock()
+	return sql.OpenDB(replayConnector{tape: t})
+}
+
+// Done returns an error if interactions on the tape have not been replayed.
+func (t *Tape) Done() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if n := len(t.interactions) - t.pos; n > 0 {
+		ia := t.interactions[t.pos]
+		return fmt.Errorf("txtest: %d interactions not replayed, next is %s %q", n, ia.Kind, ia.Query)
+	}
+	return nil
+}
+
+func (t *Tape) add(ia *Interaction) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.interactions = append(t.interactions, ia)
+}
+
+// next returns the next interaction if it matches the call.
+func (t *Tape) next(kind Kind, query string, args []driver.NamedValue) (*Interaction, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.pos >= len(t.interactions) {
+		return nil, fmt.Errorf("txtest: replay %s %q: tape exhausted", kind, query)
+	}
+	ia := t.interactions[t.pos]
+	if ia.Kind != kind || ia.Query != query || ia.Args != fingerprint(args) {
+		return nil, fmt.Errorf("txtest: replay %s %q: tape has %s %q at %d", kind, query, ia.Kind, ia.Query, t.pos)
+	}
+	t.pos++
+	return ia, nil
+}
+
+// fingerprint returns a hash of args.
+func fingerprint(args []driver.NamedValue) string {
+	if len(args) == 0 {
+		return ""
+	}
+	h := fnv.New64a()
+	for _, a := range args {
+		fmt.Fprintf(h, "%s:%d:%T:%v;", a.Name, a.Ordinal, a.Value, a.Value)
+	}
+	return fmt.Sprintf("%016x", h.Sum64())
+}
+
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// replayErr returns the error an interaction failed with.
+func replayErr(ia *Interaction) error {
+	if ia.Err == "" {
+		return nil
+	}
+	return errors.New(ia.Err)
+}
+
+// dsnConnector opens connections through a driver without OpenConnector.
+type dsnConnector struct {
+	d   driver.Driver
+	dsn string
+}
+
+func (c dsnConnector) Connect(context.Context) (driver.Conn, error) {
+	return c.d.Open(c.dsn)
+}
+
+func (c dsnConnector) Driver() driver.Driver {
+	return c.d
+}
+
+// recordConnector wraps the connections of c to record on tape.
+type recordConnector struct {
+	c    driver.Connector
+	tape *Tape
+}
+
+func (c recordConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.c.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &recordConn{conn: conn, tape: c.tape}, nil
+}
+
+func (c recordConnector) Driver() driver.Driver {
+	return c.c.Driver()
+}
+
+// recordConn records the calls to conn.
+type recordConn struct {
+	conn driver.Conn
+	tape *Tape
+}
+
+var (
+	_ driver.ConnBeginTx        = (*recordConn)(nil)
+	_ driver.ConnPrepareContext = (*recordConn)(nil)
+	_ driver.ExecerContext      = (*recordConn)(nil)
+	_ driver.QueryerContext     = (*recordConn)(nil)
+	_ driver.NamedValueChecker  = (*recordConn)(nil)
+)
+
+func (c *recordConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *recordConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	var s driver.Stmt
+	var err error
+	if pc, ok := c.conn.(driver.ConnPrepareContext); ok {
+		s, err = pc.PrepareContext(ctx, query)
+	} else {
+		s, err = c.conn.Prepare(query)
+	}
+	c.tape.add(&Interaction{Kind: KindPrepare, Query: query, Err: errString(err)})
+	if err != nil {
+		return nil, err
+	}
+	return &recordStmt{stmt: s, query: query, tape: c.tape}, nil
+}
+
+func (c *recordConn) Close() error {
+	return c.conn.Close()
+}
+
+func (c *recordConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *recordConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	var tx driver.Tx
+	var err error
+	if bc, ok := c.conn.(driver.ConnBeginTx); ok {
+		tx, err = bc.BeginTx(ctx, opts)
+	} else {
+		tx, err = c.conn.Begin()
+	}
+	c.tape.add(&Interaction{Kind: KindBegin, Err: errString(err)})
+	if err != nil {
+		return nil, err
+	}
+	return recordTx{tx: tx, tape: c.tape}, nil
+}
+
+func (c *recordConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	ec, ok := c.conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	res, err := ec.ExecContext(ctx, query, args)
+	if errors.Is(err, driver.ErrSkip) {
+		return nil, err
+	}
+	return recordResult(c.tape, query, args, res, err)
+}
+
+func (c *recordConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	qc, ok := c.conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	r, err := qc.QueryContext(ctx, query, args)
+	if errors.Is(err, driver.ErrSkip) {
+		return nil, err
+	}
+	return recordRows(c.tape, query, args, r, err)
+}
+
+func (c *recordConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if nc, ok := c.conn.(driver.NamedValueChecker); ok {
+		return nc.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+// recordStmt records the executions of stmt.
+type recordStmt struct {
+	stmt  driver.Stmt
+	query string
+	tape  *Tape
+}
+
+func (s *recordStmt) Close() error {
+	return s.stmt.Close()
+}
+
+func (s *recordStmt) NumInput() int {
+	return s.stmt.NumInput()
+}
+
+func (s *recordStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.ExecContext(context.Background(), named(args))
+}
+
+func (s *recordStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.QueryContext(context.Background(), named(args))
+}
+
+func (s *recordStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	var res driver.Result
+	var err error
+	if ec, ok := s.stmt.(driver.StmtExecContext); ok {
+		res, err = ec.ExecContext(ctx, args)
+	} else {
+		res, err = s.stmt.Exec(values(args))
+	}
+	return recordResult(s.tape, s.query, args, res, err)
+}
+
+func (s *recordStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	var r driver.Rows
+	var err error
+	if qc, ok := s.stmt.(driver.StmtQueryContext); ok {
+		r, err = qc.QueryContext(ctx, args)
+	} else {
+		r, err = s.stmt.Query(values(args))
+	}
+	return recordRows(s.tape, s.query, args, r, err)
+}
+
+func (s *recordStmt) CheckNamedValue(nv *driver.NamedValue) error {
+	if nc, ok := s.stmt.(driver.NamedValueChecker); ok {
+		return nc.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+// values converts named arguments for the legacy driver interface.
+func values(args []driver.NamedValue) []driver.Value {
+	v := make([]driver.Value, len(args))
+	for i, a := range args {
+		v[i] = a.Value
+	}
+	return v
+}
+
+func recordResult(t *Tape, query string, args []driver.NamedValue, res driver.Result, err error) (driver.Result, error) {
+	ia := &Interaction{Kind: KindExec, Query: query, Args: fingerprint(args), Err: errString(err)}
+	if err == nil {
+		ia.LastInsertID, _ = res.LastInsertId()
+		ia.RowsAffected, _ = res.RowsAffected()
+	}
+	t.add(ia)
+	return res, err
+}
+
+func recordRows(t *Tape, query string, args []driver.NamedValue, r driver.Rows, err error) (driver.Rows, error) {
+	ia := &Interaction{Kind: KindQuery, Query: query, Args: fingerprint(args), Err: errString(err)}
+	t.add(ia)
+	if err != nil {
+		return nil, err
+	}
+	ia.Columns = r.Columns()
+	return &recordedRows{rows: r, ia: ia, tape: t}, nil
+}
+
+// recordedRows copies the rows read from rows to its interaction.
+type recordedRows struct {
+	rows driver.Rows
+	ia   *Interaction
+	tape *Tape
+}
+
+func (r *recordedRows) Columns() []string {
+	return r.rows.Columns()
+}
+
+func (r *recordedRows) Close() error {
+	return r.rows.Close()
+}
+
+func (r *recordedRows) Next(dest []driver.Value) error {
+	if err := r.rows.Next(dest); err != nil {
+		if err != io.EOF {
+			r.tape.mu.Lock()
+			r.ia.Err = err.Error()
+			r.tape.mu.Unlock()
+		}
+		return err
+	}
+	row := make([]Value, len(dest))
+	for i, v := range dest {
+		if b, ok := v.([]byte); ok {
+			v = append([]byte(nil), b...)
+		}
+		row[i] = Value{V: v}
+	}
+	r.tape.mu.Lock()
+	r.ia.Rows = append(r.ia.Rows, row)
+	r.tape.mu.Unlock()
+	return nil
+}
+
+// recordTx records the end of tx.
+type recordTx struct {
+	tx   driver.Tx
+	tape *Tape
+}
+
+func (t recordTx) Commit() error {
+	err := t.tx.Commit()
+	t.tape.add(&Interaction{Kind: KindCommit, Err: errString(err)})
+	return err
+}
+
+func (t recordTx) Rollback() error {
+	err := t.tx.Rollback()
+	t.tape.add(&Interaction{Kind: KindRollback, Err: errString(err)})
+	return err
+}
+
+// replayConnector opens connections answering from tape.
+type replayConnector struct {
+	tape *Tape
+}
+
+func (c replayConnector) Connect(context.Context) (driver.Conn, error) {
+	return &replayConn{tape: c.tape}, nil
+}
+
+func (c replayConnector) Driver() driver.Driver {
+	return mockDriver{}
+}
+
+// replayConn answers every call with the next interaction on its tape.
+type replayConn struct {
+	tape *Tape
+}
+
+var (
+	_ driver.ConnBeginTx        = (*replayConn)(nil)
+	_ driver.ConnPrepareContext = (*replayConn)(nil)
+	_ driver.ExecerContext      = (*replayConn)(nil)
+	_ driver.QueryerContext     = (*replayConn)(nil)
+)
+
+func (c *replayConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *replayConn) PrepareContext(_ context.Context, query string) (driver.Stmt, error) {
+	ia, err := c.tape.next(KindPrepare, query, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := replayErr(ia); err != nil {
+		return nil, err
+	}
+	return &replayStmt{tape: c.tape, query: query}, nil
+}
+
+func (c *replayConn) Close() error {
+	return nil
+}
+
+func (c *replayConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *replayConn) BeginTx(context.Context, driver.TxOptions) (driver.Tx, error) {
+	ia, err := c.tape.next(KindBegin, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := replayErr(ia); err != nil {
+		return nil, err
+	}
+	return replayTx{tape: c.tape}, nil
+}
+
+func (c *replayConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	return c.tape.exec(query, args)
+}
+
+func (c *replayConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	return c.tape.query(query, args)
+}
+
+// replayStmt answers the executions of a statement prepared on a replayConn.
+type replayStmt struct {
+	tape  *Tape
+	query string
+}
+
+func (s *replayStmt) Close() error {
+	return nil
+}
+
+func (s *replayStmt) NumInput() int {
+	return -1
+}
+
+func (s *replayStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.tape.exec(s.query, named(args))
+}
+
+func (s *replayStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.tape.query(s.query, named(args))
+}
+
+func (s *replayStmt) ExecContext(_ context.Context, args []driver.NamedValue) (driver.Result, error) {
+	return s.tape.exec(s.query, args)
+}
+
+func (s *replayStmt) QueryContext(_ context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	return s.tape.query(s.query, args)
+}
+
+func (t *Tape) exec(query string, args []driver.NamedValue) (driver.Result, error) {
+	ia, err := t.next(KindExec, query, args)
+	if err != nil {
+		return nil, err
+	}
+	if err := replayErr(ia); err != nil {
+		return nil, err
+	}
+	return result{lastID: ia.LastInsertID, affected: ia.RowsAffected}, nil
+}
+
+func (t *Tape) query(query string, args []driver.NamedValue) (driver.Rows, error) {
+	ia, err := t.next(KindQuery, query, args)
+	if err != nil {
+		return nil, err
+	}
+	if ia.Err != "" && len(ia.Columns) == 0 {
+		return nil, replayErr(ia)
+	}
+	r := &rows{columns: ia.Columns, err: replayErr(ia)}
+	for _, row := range ia.Rows {
+		vals := make([]any, len(row))
+		for i, v := range row {
+			vals[i] = v.V
+		}
+		r.values = append(r.values, vals)
+	}
+	return r, nil
+}
+
+// replayTx answers the end of a transaction from its tape.
+type replayTx struct {
+	tape *Tape
+}
+
+func (t replayTx) Commit() error {
+	ia, err := t.tape.next(KindCommit, "", nil)
+	if err != nil {
+		return err
+	}
+	return replayErr(ia)
+}
+
+func (t replayTx) Rollback() error {
+	ia, err := t.tape.next(KindRollback, "", nil)
+	if err != nil {
+		return err
+	}
+	return replayErr(ia)
+}
@@ -0,0 +1,99 @@
+package txnode
+
+import (
+	"context"
+	"database/sql"
+)
+
+// Session runs statements in the transaction of a unit of work. Repositories
+// take a Session instead of a *sql.DB or *TxNode, so that all of them share
+// the transaction without passing the node and the database around.
+type Session interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *Row
+}
+
+// UnitOfWork owns one node and binds repositories to its transaction, so that
+// the operations of all of them are committed or rolled back together.
+//
+//	uow := txnode.NewUnitOfWork(db)
+//	defer uow.Close()
+//	orders := txnode.Repo(uow, NewOrderRepo)
+//	stock := txnode.Repo(uow, NewStockRepo)
+//	// ...
+//	return uow.Commit()
+type UnitOfWork struct {
+	db  DB
+	txn *TxNode
+}
+
+// NewUnitOfWork returns a unit of work whose transaction is begun on db by its
+// first statement and configured with opts.
+func NewUnitOfWork(db DB, opts ...Option) *UnitOfWork {
+	return &UnitOfWork{db: db, txn: New(opts...)}
+}
+
+// RunUnitOfWork calls fn with a unit of work bound to a transaction begun on
+// db, committing it if fn returns nil and rolling it back otherwise, as Run
+// does.
+func RunUnitOfWork(
+	ctx context.Context,
+	db DB,
+	fn func(ctx context.Context, uow *UnitOfWork) error,
+	opts ...Option,
+) error {
+	return Run(ctx, db, func(ctx context.Context, txn *TxNode) error {
+		return fn(ctx, &UnitOfWork{db: db, txn: txn})
+	}, opts...)
+}
+
+// Repo returns the repository built by newRepo on the session of u.
+func Repo[R any](u *UnitOfWork, newRepo func(s Session) R) R {
+	return newRepo(u.Session())
+}
+
+// Node returns the node of the unit of work.
+func (u *UnitOfWork) Node() *TxNode {
+	return u.txn
+}
+
+// Session returns the session running statements in the transaction of u.
+func (u *UnitOfWork) Session() Session {
+	return session{db: u.db, txn: u.txn}
+}
+
+// Commit commits the operations of all repositories of u.
+func (u *UnitOfWork) Commit() error {
+	u.txn.SetEnd()
+	return u.txn.CommitIfNeeded()
+}
+
+// Rollback rolls back the operations of all repositories of u.
+func (u *UnitOfWork) Rollback() error {
+	return u.txn.RollbackTransaction()
+}
+
+// Close rolls back the transaction of u if it is still active, see
+// TxNode.Close.
+func (u *UnitOfWork) Close() error {
+	return u.txn.Close()
+}
+
+// session is the Session of a unit of work.
+type session struct {
+	db  DB
+	txn *TxNode
+}
+
+func (s session) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return s.txn.ExecContext(ctx, s.db, query, args...)
+}
+
+func (s session) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return s.txn.QueryContext(ctx, s.db, query, args...)
+}
+
+func (s session) QueryRowContext(ctx context.Context, query string, args ...any) *Row {
+	return s.txn.QueryRowContext(ctx, s.db, query, args...)
+}
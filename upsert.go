@@ -0,0 +1,108 @@
+package txnode
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Upsert inserts row, keyed by column name, into table or, if it conflicts
+// with an existing row on conflictColumns, updates the other columns of that
+// row, in the node's transaction, beginning it on db if needed. If row has no
+// other columns, a conflicting row is left as it is.
+//
+// Postgres and SQLite use INSERT ... ON CONFLICT and need conflictColumns to
+// match a unique index. MySQL uses INSERT ... ON DUPLICATE KEY UPDATE, which
+// applies to any unique key of the table, so conflictColumns only tells which
+// columns not to update. SQL Server fails with ErrUnsupported.
+func (txn *TxNode) Upsert(
+	ctx context.Context,
+	db DB,
+	table string,
+	conflictColumns []string,
+	row map[string]any,
+) (sql.Result, error) {
+	query, args, err := upsertSQL(txn.dialect(), txn.bindvar(), table, conflictColumns, row)
+	if err != nil {
+		return nil, err
+	}
+	return txn.ExecContext(ctx, db, query, args...)
+}
+
+// upsertSQL returns the statement upserting row in dialect d, with
+// placeholders of style bv, and its arguments.
+func upsertSQL(d Dialect, bv Bindvar, table string, conflict []string, row map[string]any) (string, []any, error) {
+	if d == DialectSQLServer {
+		return "", nil, fmt.Errorf("txnode: upsert into %s: %w", table, ErrUnsupported)
+	}
+	if !isQualifiedIdentifier(table) {
+		return "", nil, fmt.Errorf("txnode: invalid table %q", table)
+	}
+	if len(row) == 0 {
+		return "", nil, fmt.Errorf("txnode: upsert into %s: no columns", table)
+	}
+	if len(conflict) == 0 && d != DialectMySQL {
+		return "", nil, fmt.Errorf("txnode: upsert into %s: no conflict columns", table)
+	}
+	for _, col := range conflict {
+		if !isIdentifier(col) {
+			return "", nil, fmt.Errorf("txnode: invalid column %q", col)
+		}
+	}
+
+	columns := make([]string, 0, len(row))
+	for col := range row {
+		if !isIdentifier(col) {
+			return "", nil, fmt.Errorf("txnode: invalid column %q", col)
+		}
+		columns = append(columns, col)
+	}
+	slices.Sort(columns)
+
+	args := make([]any, len(columns))
+	placeholders := make([]string, len(columns))
+	var update []string
+	for i, col := range columns {
+		args[i] = row[col]
+		placeholders[i] = bv.placeholder(i + 1)
+		if !slices.Contains(conflict, col) {
+			update = append(update, col)
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("INSERT INTO " + table + " (" + strings.Join(columns, ", ") + ")")
+	b.WriteString(" VALUES (" + strings.Join(placeholders, ", ") + ")")
+
+	if d == DialectMySQL {
+		if len(update) == 0 {
+			// A no-op assignment keeps the conflicting row as it is.
+			b.WriteString(" ON DUPLICATE KEY UPDATE " + columns[0] + " = " + columns[0])
+			return b.String(), args, nil
+		}
+		b.WriteString(" ON DUPLICATE KEY UPDATE ")
+		for i, col := range update {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			b.WriteString(col + " = VALUES(" + col + ")")
+		}
+		return b.String(), args, nil
+	}
+
+	b.WriteString(" ON CONFLICT (" + strings.Join(conflict, ", ") + ")")
+	if len(update) == 0 {
+		b.WriteString(" DO NOTHING")
+		return b.String(), args, nil
+	}
+	b.WriteString(" DO UPDATE SET ")
+	for i, col := range update {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(col + " = EXCLUDED." + col)
+	}
+	return b.String(), args, nil
+}
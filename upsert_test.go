@@ -0,0 +1,104 @@
+package txnode_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+
+	"github.com/MartellOnell/txnode"
+)
+
+func TestUpsert(t *testing.T) {
+	tests := []struct {
+		name     string
+		d        txnode.Dialect
+		conflict []string
+		row      map[string]any
+		want     string
+		args     []driver.Value
+	}{
+		{
+			"postgres", txnode.DialectPostgres, []string{"id"}, map[string]any{"id": 1, "name": "a"},
+			"INSERT INTO users (id, name) VALUES ($1, $2) ON CONFLICT (id) DO UPDATE SET name = EXCLUDED.name",
+			[]driver.Value{1, "a"},
+		},
+		{
+			"postgres no update", txnode.DialectPostgres, []string{"id"}, map[string]any{"id": 1},
+			"INSERT INTO users (id) VALUES ($1) ON CONFLICT (id) DO NOTHING",
+			[]driver.Value{1},
+		},
+		{
+			"sqlite", txnode.DialectSQLite, []string{"id"}, map[string]any{"id": 1, "name": "a"},
+			"INSERT INTO users (id, name) VALUES (?, ?) ON CONFLICT (id) DO UPDATE SET name = EXCLUDED.name",
+			[]driver.Value{1, "a"},
+		},
+		{
+			"mysql", txnode.DialectMySQL, []string{"id"}, map[string]any{"id": 1, "name": "a"},
+			"INSERT INTO users (id, name) VALUES (?, ?) ON DUPLICATE KEY UPDATE name = VALUES(name)",
+			[]driver.Value{1, "a"},
+		},
+		{
+			"mysql no update", txnode.DialectMySQL, []string{"id"}, map[string]any{"id": 1},
+			"INSERT INTO users (id) VALUES (?) ON DUPLICATE KEY UPDATE id = id",
+			[]driver.Value{1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+			mock.ExpectBegin()
+			mock.ExpectExec(tt.want).WithArgs(tt.args...).WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectCommit()
+
+			txn := txnode.New(txnode.WithDialect(tt.d))
+			txn.SetEnd()
+			if _, err := txn.Upsert(context.Background(), db, "users", tt.conflict, tt.row); err != nil {
+				t.Fatal(err)
+			}
+			if err := txn.CommitIfNeeded(); err != nil {
+				t.Fatal(err)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestUpsertInvalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		d        txnode.Dialect
+		table    string
+		conflict []string
+		row      map[string]any
+	}{
+		{"table", txnode.DialectPostgres, "users u", []string{"id"}, map[string]any{"id": 1}},
+		{"column", txnode.DialectPostgres, "users", []string{"id"}, map[string]any{"id = 1 --": 1}},
+		{"no columns", txnode.DialectPostgres, "users", []string{"id"}, nil},
+		{"no conflict columns", txnode.DialectPostgres, "users", nil, map[string]any{"id": 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			txn := txnode.New(txnode.WithDialect(tt.d))
+			if _, err := txn.Upsert(context.Background(), nil, tt.table, tt.conflict, tt.row); err == nil {
+				t.Error("Upsert() succeeded, want an error")
+			}
+		})
+	}
+}
+
+func TestUpsertSQLServerUnsupported(t *testing.T) {
+	txn := txnode.New(txnode.WithDialect(txnode.DialectSQLServer))
+	_, err := txn.Upsert(context.Background(), nil, "users", []string{"id"}, map[string]any{"id": 1})
+	if !errors.Is(err, txnode.ErrUnsupported) {
+		t.Errorf("Upsert() = %v, want ErrUnsupported", err)
+	}
+}
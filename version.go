@@ -0,0 +1,55 @@
+package txnode
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrStaleVersion is returned by UpdateVersioned when no row matched the
+// expected version, because another transaction changed or deleted the row
+// since it was read.
+var ErrStaleVersion = errors.New("stale row version")
+
+// UpdateVersioned executes query, an UPDATE guarded by a version check, in
+// the node's transaction, beginning it on db if needed. expectedVersion is
+// passed after args, so it binds the last placeholder of query:
+//
+//	txn.UpdateVersioned(ctx, db,
+//		"UPDATE accounts SET balance = $1, version = version + 1 WHERE id = $2 AND version = $3",
+//		acc.Version, acc.Balance, acc.ID)
+//
+// If the update affects no row, UpdateVersioned fails with ErrStaleVersion.
+// See WithStaleRetry to run the whole transaction again in that case.
+func (txn *TxNode) UpdateVersioned(
+	ctx context.Context,
+	db DB,
+	query string,
+	expectedVersion any,
+	args ...any,
+) error {
+	args = append(args[:len(args):len(args)], expectedVersion)
+	res, err := txn.ExecContext(ctx, db, query, args...)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("txnode: versioned update: %w", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("txnode: versioned update at version %v: %w", expectedVersion, ErrStaleVersion)
+	}
+	return nil
+}
+
+// WithStaleRetry makes Run run the whole closure again, in a new transaction,
+// when it fails with ErrStaleVersion, up to attempts attempts in total. The
+// closure then re-reads the rows and reapplies its changes at their current
+// version. The retries are immediate and independent of the RetryPolicy.
+func WithStaleRetry(attempts int) Option {
+	return func(c *config) {
+		c.staleRetry = attempts
+	}
+}
@@ -0,0 +1,66 @@
+package txnode
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+)
+
+// WithCancelRollback starts a watchdog when the transaction begins. Once the
+// context passed at begin is done, the node rolls the transaction back right
+// away instead of leaving it to fail on the next statement or commit. The node
+// is then aborted: its methods return an error that wraps ErrAborted and the
+// context's cancellation cause, see Err.
+func WithCancelRollback() Option {
+	return func(c *config) {
+		c.cancelRollback = true
+	}
+}
+
+// Err returns the reason the node was aborted, or nil if it was not.
+func (txn *TxNode) Err() error {
+	if txn == nil {
+		return nil
+	}
+
+	txn.mu.Lock()
+	defer txn.mu.Unlock()
+	return txn.aborted
+}
+
+// watch starts the cancellation watchdog for ctx if it is enabled.
+func (txn *TxNode) watch(ctx context.Context) {
+	if !txn.cfg.cancelRollback {
+		return
+	}
+
+	txn.stopWatch = context.AfterFunc(ctx, func() {
+		txn.abort(fmt.Errorf("%w: %w", ErrAborted, context.Cause(ctx)))
+	})
+}
+
+// abort rolls back the transaction from outside the goroutine driving the
+// chain and records reason. The resources of the node are freed by the next
+// call to RollbackTransaction or CommitIfNeeded.
+func (txn *TxNode) abort(reason error) {
+	txn.mu.Lock()
+	if txn.aborted != nil || !txn.ended.IsZero() {
+		txn.mu.Unlock()
+		return
+	}
+	reason = txn.wrapErr(reason)
+	txn.aborted = reason
+	committing := txn.committing
+	txn.mu.Unlock()
+
+	txn.log(slog.LevelWarn, "abort transaction", txn.debugAttrs(slog.Any("reason", reason))...)
+	if !committing {
+		txn.resetSession(txn.tx)
+	}
+	_ = txn.tx.Rollback()
+	if committing {
+		return
+	}
+	txn.notifyEnd(false, reason)
+	txn.runRollbackHooks(reason)
+}
@@ -0,0 +1,31 @@
+package txnode
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Wrap returns a node that adopts an already started transaction instead of
+// beginning one. Options that only affect beginning, such as WithTxOptions,
+// have no effect on a wrapped node.
+func Wrap(tx *sql.Tx, opts ...Option) *TxNode {
+	return &TxNode{
+		tx:    tx,
+		cfg:   newConfig(opts),
+		id:    newID(),
+		began: time.Now(),
+		state: StateActive,
+	}
+}
+
+// WithExternalOwnership marks the transaction as owned by someone else.
+// CommitIfNeeded then leaves the commit to the owner, only releases the
+// resources held by the node and hands the transaction off, see
+// StateHandedOff: later calls to RollbackTransaction and Close do nothing, so
+// that deferring them does not discard the owner's work. Before that,
+// RollbackTransaction still rolls the owner's transaction back.
+func WithExternalOwnership() Option {
+	return func(c *config) {
+		c.external = true
+	}
+}
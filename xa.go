@@ -0,0 +1,312 @@
+package txnode
+
+import (
+	"context"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// XID identifies a MySQL XA transaction.
+type XID struct {
+	// GTRID is the global transaction identifier, shared by all branches of
+	// a distributed transaction.
+	GTRID string
+	// BQUAL is the branch qualifier, distinguishing the branches.
+	BQUAL string
+	// FormatID identifies the format of GTRID and BQUAL. MySQL uses 1 by
+	// default.
+	FormatID int
+}
+
+// NewXID returns an XID with a random GTRID and the given branch qualifier.
+func NewXID(bqual string) XID {
+	return XID{GTRID: GIDPrefix + newID(), BQUAL: bqual, FormatID: 1}
+}
+
+// String formats x as it appears in XA statements. GTRID and BQUAL are
+// written as hexadecimal literals, so they may hold arbitrary bytes.
+func (x XID) String() string {
+	s := "X'" + hex.EncodeToString([]byte(x.GTRID)) + "'"
+	s += ",X'" + hex.EncodeToString([]byte(x.BQUAL)) + "'"
+	return s + "," + strconv.Itoa(x.FormatID)
+}
+
+// XANode is a node driving a MySQL XA transaction on one connection instead
+// of a database/sql transaction, so that it can take part in a distributed
+// transaction. It has the chaining semantics of TxNode: every node begins the
+// XA transaction lazily with XA START, and CommitIfNeeded commits it in one
+// phase if the node is the end of the chain. For a two-phase commit, call
+// Prepare on every participant and then CommitXA or RollbackXA with their
+// XIDs; prepared XA transactions survive disconnects and server restarts and
+// are found by RecoverXA.
+//
+// The node owns conn and closes it once the XA transaction is committed or
+// rolled back. After Prepare the connection is kept, so that the node can
+// finish the transaction with CommitPrepared or RollbackTransaction; call
+// Close instead if the outcome is resolved elsewhere with CommitXA or
+// RollbackXA.
+type XANode struct {
+	conn  *sql.Conn
+	xid   XID
+	isEnd bool
+	state State
+}
+
+// NewXA creates a node that runs the XA transaction xid on conn.
+func NewXA(conn *sql.Conn, xid XID) *XANode {
+	return &XANode{conn: conn, xid: xid}
+}
+
+// XID returns the identifier of the node's XA transaction.
+func (x *XANode) XID() XID {
+	return x.xid
+}
+
+// State returns the state of the node's XA transaction.
+func (x *XANode) State() State {
+	return x.state
+}
+
+// UnsetEnd marks this node as not being the end of the transaction chain.
+func (x *XANode) UnsetEnd() {
+	x.isEnd = false
+}
+
+// SetEnd marks this node as the end of the transaction chain.
+func (x *XANode) SetEnd() {
+	x.isEnd = true
+}
+
+// begin starts the XA transaction if it has not been started yet.
+func (x *XANode) begin(ctx context.Context) error {
+	switch x.state {
+	case StateIdle:
+	case StateActive:
+		return nil
+	default:
+		return fmt.Errorf("txnode: xa %s: %w", x.state, sql.ErrTxDone)
+	}
+
+	if err := x.exec(ctx, "XA START "+x.xid.String()); err != nil {
+		return err
+	}
+	x.state = StateActive
+	return nil
+}
+
+// PrepareQuery prepares a SQL statement in the XA transaction.
+func (x *XANode) PrepareQuery(ctx context.Context, query string) (*sql.Stmt, error) {
+	if err := x.begin(ctx); err != nil {
+		return nil, err
+	}
+	return x.conn.PrepareContext(ctx, query)
+}
+
+// ExecContext executes a query without returning rows in the XA transaction.
+func (x *XANode) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	if err := x.begin(ctx); err != nil {
+		return nil, err
+	}
+	return x.conn.ExecContext(ctx, query, args...)
+}
+
+// QueryContext executes a query that returns rows in the XA transaction.
+func (x *XANode) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	if err := x.begin(ctx); err != nil {
+		return nil, err
+	}
+	return x.conn.QueryContext(ctx, query, args...)
+}
+
+// QueryRowContext executes a query that is expected to return at most one row
+// in the XA transaction.
+func (x *XANode) QueryRowContext(ctx context.Context, query string, args ...any) *Row {
+	if err := x.begin(ctx); err != nil {
+		return &Row{err: err}
+	}
+	return &Row{row: x.conn.QueryRowContext(ctx, query, args...)}
+}
+
+// Prepare ends the XA transaction and prepares it for a two-phase commit.
+func (x *XANode) Prepare(ctx context.Context) error {
+	if x.state != StateActive {
+		return fmt.Errorf("txnode: xa prepare: %w", ErrNotStarted)
+	}
+
+	if err := x.exec(ctx, "XA END "+x.xid.String()); err != nil {
+		return errors.Join(err, x.rollback(ctx))
+	}
+	if err := x.exec(ctx, "XA PREPARE "+x.xid.String()); err != nil {
+		return errors.Join(err, x.rollback(ctx))
+	}
+	x.state = StatePrepared
+	return nil
+}
+
+// CommitPrepared commits the XA transaction prepared with Prepare, as the
+// second phase of a two-phase commit, and closes the connection.
+func (x *XANode) CommitPrepared(ctx context.Context) error {
+	if x.state != StatePrepared {
+		return fmt.Errorf("txnode: xa commit prepared: %w", ErrNotStarted)
+	}
+	if x.conn == nil {
+		return fmt.Errorf("txnode: xa commit prepared: %w", sql.ErrConnDone)
+	}
+
+	if err := CommitXA(ctx, x.conn, x.xid); err != nil {
+		return err
+	}
+	x.state = StateCommitted
+	return x.release()
+}
+
+// Close releases the connection of the node without ending the XA
+// transaction. A prepared XA transaction survives it, to be resolved with
+// CommitXA or RollbackXA on another connection.
+func (x *XANode) Close() error {
+	return x.release()
+}
+
+// CommitIfNeeded commits the XA transaction in one phase, only if this node
+// is marked as the end of the chain.
+func (x *XANode) CommitIfNeeded(ctx context.Context) error {
+	if !x.isEnd || x.state != StateActive {
+		return nil
+	}
+
+	if err := x.exec(ctx, "XA END "+x.xid.String()); err != nil {
+		return errors.Join(err, x.rollback(ctx))
+	}
+	if err := x.exec(ctx, "XA COMMIT "+x.xid.String()+" ONE PHASE"); err != nil {
+		x.state = StateRolledBack
+		return errors.Join(err, x.release())
+	}
+	x.state = StateCommitted
+	return x.release()
+}
+
+// RollbackTransaction rolls back the XA transaction if it is active or
+// prepared.
+func (x *XANode) RollbackTransaction(ctx context.Context) error {
+	switch x.state {
+	case StateActive:
+		if err := x.exec(ctx, "XA END "+x.xid.String()); err != nil {
+			return errors.Join(err, x.rollback(ctx))
+		}
+		return x.rollback(ctx)
+	case StatePrepared:
+		if x.conn == nil {
+			return fmt.Errorf("txnode: xa rollback prepared: %w", sql.ErrConnDone)
+		}
+		if err := RollbackXA(ctx, x.conn, x.xid); err != nil {
+			return err
+		}
+		x.state = StateRolledBack
+		return x.release()
+	default:
+		return nil
+	}
+}
+
+// rollback rolls back the ended XA transaction and closes the connection.
+func (x *XANode) rollback(ctx context.Context) error {
+	err := x.exec(ctx, "XA ROLLBACK "+x.xid.String())
+	x.state = StateRolledBack
+	return errors.Join(err, x.release())
+}
+
+// release closes the connection of the node once, when its XA transaction
+// has ended.
+func (x *XANode) release() error {
+	if x.conn == nil {
+		return nil
+	}
+	err := x.conn.Close()
+	x.conn = nil
+	return err
+}
+
+func (x *XANode) exec(ctx context.Context, query string) error {
+	if _, err := x.conn.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("txnode: %s: %w", query, err)
+	}
+	return nil
+}
+
+// CommitXA commits the prepared XA transaction xid on db.
+func CommitXA(ctx context.Context, db Execer, xid XID) error {
+	if _, err := db.ExecContext(ctx, "XA COMMIT "+xid.String()); err != nil {
+		return fmt.Errorf("txnode: xa commit %s: %w", xid.GTRID, err)
+	}
+	return nil
+}
+
+// RollbackXA rolls back the prepared XA transaction xid on db.
+func RollbackXA(ctx context.Context, db Execer, xid XID) error {
+	if _, err := db.ExecContext(ctx, "XA ROLLBACK "+xid.String()); err != nil {
+		return fmt.Errorf("txnode: xa rollback %s: %w", xid.GTRID, err)
+	}
+	return nil
+}
+
+// RecoverXA returns the prepared XA transactions of the server, as listed by
+// XA RECOVER. After a crash they must be resolved with CommitXA or RollbackXA;
+// the XIDs generated by NewXID have a GTRID starting with GIDPrefix.
+func RecoverXA(ctx context.Context, db Querier) ([]XID, error) {
+	rows, err := db.QueryContext(ctx, "XA RECOVER")
+	if err != nil {
+		return nil, fmt.Errorf("txnode: xa recover: %w", err)
+	}
+	defer rows.Close()
+
+	var out []XID
+	for rows.Next() {
+		var (
+			formatID, gtridLen, bqualLen int
+			data                         []byte
+		)
+		if err := rows.Scan(&formatID, &gtridLen, &bqualLen, &data); err != nil {
+			return nil, fmt.Errorf("txnode: xa recover: %w", err)
+		}
+		if gtridLen < 0 || bqualLen < 0 || gtridLen+bqualLen > len(data) {
+			return nil, fmt.Errorf("txnode: xa recover: malformed xid %q", data)
+		}
+		out = append(out, XID{
+			GTRID:    string(data[:gtridLen]),
+			BQUAL:    string(data[gtridLen : gtridLen+bqualLen]),
+			FormatID: formatID,
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("txnode: xa recover: %w", err)
+	}
+	return out, nil
+}
+
+// ResolveXA resolves the prepared XA transactions listed by RecoverXA whose
+// GTRID starts with GIDPrefix: those for which commit returns true are
+// committed, the others rolled back. XA transactions of other applications
+// are left alone.
+func ResolveXA(ctx context.Context, db DB, commit func(xid XID) bool) error {
+	xids, err := RecoverXA(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, xid := range xids {
+		if !strings.HasPrefix(xid.GTRID, GIDPrefix) {
+			continue
+		}
+		if commit(xid) {
+			errs = append(errs, CommitXA(ctx, db, xid))
+		} else {
+			errs = append(errs, RollbackXA(ctx, db, xid))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,100 @@
+// Package zaplog routes txnode logs into a zap logger.
+//
+//	txn := txnode.New(zaplog.WithLogger(logger))
+package zaplog
+
+import (
+	"context"
+	"log/slog"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/MartellOnell/txnode"
+)
+
+// Logger is a txnode.Logger that writes to a zap logger.
+type Logger struct {
+	log *zap.Logger
+}
+
+var _ txnode.Logger = (*Logger)(nil)
+
+// New returns a Logger that writes to log.
+func New(log *zap.Logger) *Logger {
+	return &Logger{log: log}
+}
+
+// WithLogger returns a txnode option that makes the node log to log.
+func WithLogger(log *zap.Logger) txnode.Option {
+	return txnode.WithCustomLogger(New(log))
+}
+
+// Log implements txnode.Logger.
+func (l *Logger) Log(_ context.Context, level slog.Level, msg string, attrs ...slog.Attr) {
+	ce := l.log.Check(zapLevel(level), msg)
+	if ce == nil {
+		return
+	}
+	ce.Write(fields(attrs)...)
+}
+
+// Enabled reports whether the zap logger records entries at level.
+func (l *Logger) Enabled(_ context.Context, level slog.Level) bool {
+	return l.log.Core().Enabled(zapLevel(level))
+}
+
+// zapLevel maps a slog level to the closest zap level.
+func zapLevel(level slog.Level) zapcore.Level {
+	switch {
+	case level < slog.LevelInfo:
+		return zapcore.DebugLevel
+	case level < slog.LevelWarn:
+		return zapcore.InfoLevel
+	case level < slog.LevelError:
+		return zapcore.WarnLevel
+	default:
+		return zapcore.ErrorLevel
+	}
+}
+
+// fields converts slog attributes to zap fields, keeping groups as nested
+// objects.
+func fields(attrs []slog.Attr) []zap.Field {
+	fs := make([]zap.Field, 0, len(attrs))
+	for _, a := range attrs {
+		a.Value = a.Value.Resolve()
+		if a.Equal(slog.Attr{}) {
+			continue
+		}
+		fs = append(fs, field(a))
+	}
+	return fs
+}
+
+func field(a slog.Attr) zap.Field {
+	v := a.Value
+	switch v.Kind() {
+	case slog.KindString:
+		return zap.String(a.Key, v.String())
+	case slog.KindInt64:
+		return zap.Int64(a.Key, v.Int64())
+	case slog.KindUint64:
+		return zap.Uint64(a.Key, v.Uint64())
+	case slog.KindFloat64:
+		return zap.Float64(a.Key, v.Float64())
+	case slog.KindBool:
+		return zap.Bool(a.Key, v.Bool())
+	case slog.KindDuration:
+		return zap.Duration(a.Key, v.Duration())
+	case slog.KindTime:
+		return zap.Time(a.Key, v.Time())
+	case slog.KindGroup:
+		return zap.Dict(a.Key, fields(v.Group())...)
+	}
+
+	if err, ok := v.Any().(error); ok {
+		return zap.NamedError(a.Key, err)
+	}
+	return zap.Any(a.Key, v.Any())
+}